@@ -0,0 +1,169 @@
+package ffmpego
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A Dither is an algorithm for reducing the colors of a
+// GIF animation.
+type Dither string
+
+// Dithering algorithms supported by ffmpeg's paletteuse
+// filter.
+const (
+	DitherSierra2_4a     Dither = "sierra2_4a"
+	DitherSierra2        Dither = "sierra2"
+	DitherFloydSteinberg Dither = "floyd_steinberg"
+	DitherHeckbert       Dither = "heckbert"
+	DitherBayer          Dither = "bayer"
+	DitherNone           Dither = "none"
+)
+
+// AnimationOptions configures how an animated image is
+// encoded.
+type AnimationOptions struct {
+	// Loops is the number of times the animation plays.
+	// If 0, the animation loops forever.
+	Loops int
+
+	// Dither is the dithering algorithm for GIFs.
+	// If empty, DitherSierra2_4a is used.
+	Dither Dither
+
+	// BayerScale is the scale of the bayer dithering
+	// pattern, from 0 to 5. Lower values produce a more
+	// visible pattern with smoother gradients.
+	BayerScale int
+
+	// MaxColors is the maximum number of colors in the
+	// palette of a GIF, up to 256. If 0, 256 is used.
+	MaxColors int
+
+	// Quality is the quality of lossy WebP animations, from
+	// 0 to 100. If 0, 75 is used.
+	Quality int
+
+	// Lossless enables lossless WebP encoding.
+	Lossless bool
+}
+
+// NewAnimationWriter creates a VideoWriter which encodes
+// an animated image, with each frame shown for the given
+// delay.
+//
+// The format is determined by the extension of the path,
+// which may be .gif, .webp, .png, or .apng.
+//
+// Frames written with WriteFrameDuration may use their own
+// delays instead of the default one.
+//
+// GIFs are encoded in two passes: a palette is generated
+// from all of the frames once the writer is closed, and
+// then used to encode every frame. Note that GIF delays are
+// stored in hundredths of a second.
+func NewAnimationWriter(path string, width, height int, delay time.Duration,
+	opts *AnimationOptions) (*VideoWriter, error) {
+	if delay <= 0 {
+		panic("delay must be positive")
+	}
+	encoding, err := animationEncoding(path, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "write animation")
+	}
+	fps := float64(time.Second) / float64(delay)
+	vw, err := newVideoWriter(path, width, height, fps, &VideoWriterOptions{
+		Encoding:          encoding,
+		VariableFrameRate: true,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "write animation")
+	}
+	return vw, err
+}
+
+func animationEncoding(path string, opts *AnimationOptions) (*VideoEncoding, error) {
+	if opts.Loops < 0 {
+		return nil, errors.New("loop count must not be negative")
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gif":
+		filter, err := gifPaletteFilter(opts)
+		if err != nil {
+			return nil, err
+		}
+		loop := 0
+		if opts.Loops == 1 {
+			loop = -1
+		} else if opts.Loops > 1 {
+			loop = opts.Loops - 1
+		}
+		return &VideoEncoding{
+			Codec: "gif",
+			ExtraFlags: []string{
+				"-filter:v", filter,
+				"-loop", strconv.Itoa(loop),
+				"-f", "gif",
+			},
+		}, nil
+	case ".webp":
+		quality := opts.Quality
+		if quality == 0 {
+			quality = 75
+		}
+		lossless := "0"
+		if opts.Lossless {
+			lossless = "1"
+		}
+		return &VideoEncoding{
+			Codec: "libwebp",
+			ExtraFlags: []string{
+				"-lossless", lossless,
+				"-quality", strconv.Itoa(quality),
+				"-loop", strconv.Itoa(opts.Loops),
+				"-f", "webp",
+			},
+		}, nil
+	case ".png", ".apng":
+		return &VideoEncoding{
+			Codec:       "apng",
+			PixelFormat: "rgb24",
+			ExtraFlags:  []string{"-plays", strconv.Itoa(opts.Loops), "-f", "apng"},
+		}, nil
+	default:
+		return nil, errors.New("unsupported animation format: " + filepath.Ext(path))
+	}
+}
+
+// gifPaletteFilter creates a filter which generates an
+// optimal palette for the frames and then applies it.
+func gifPaletteFilter(opts *AnimationOptions) (string, error) {
+	maxColors := opts.MaxColors
+	if maxColors == 0 {
+		maxColors = 256
+	}
+	if maxColors < 2 || maxColors > 256 {
+		return "", errors.New("max colors must be in the range [2, 256]")
+	}
+	dither := opts.Dither
+	if dither == "" {
+		dither = DitherSierra2_4a
+	}
+	use := "paletteuse=dither=" + string(dither)
+	if dither == DitherBayer {
+		if opts.BayerScale < 0 || opts.BayerScale > 5 {
+			return "", errors.New("bayer scale must be in the range [0, 5]")
+		}
+		use += fmt.Sprintf(":bayer_scale=%d", opts.BayerScale)
+	}
+	return fmt.Sprintf(
+		"split[frames][palette_in];[palette_in]palettegen=max_colors=%d:stats_mode=full[palette];"+
+			"[frames][palette]%s",
+		maxColors, use,
+	), nil
+}
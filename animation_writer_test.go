@@ -0,0 +1,98 @@
+package ffmpego
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnimationWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-animation-writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"out.gif", "out.webp", "out.apng"} {
+		if name == "out.webp" {
+			if ok, err := HasEncoder("libwebp"); err != nil {
+				t.Fatal(err)
+			} else if !ok {
+				continue
+			}
+		}
+		outPath := filepath.Join(dir, name)
+		vw, err := NewAnimationWriter(outPath, 31, 17, 100*time.Millisecond,
+			&AnimationOptions{Loops: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 5; i++ {
+			img := image.NewRGBA(image.Rect(0, 0, 31, 17))
+			for y := 0; y < 17; y++ {
+				for x := 0; x < 31; x++ {
+					img.SetRGBA(x, y, color.RGBA{R: uint8(x * 8), G: uint8(y * 15), B: uint8(i * 50),
+						A: 0xff})
+				}
+			}
+			if err := vw.WriteFrame(img); err != nil {
+				vw.Close()
+				t.Fatal(err)
+			}
+		}
+		if err := vw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		info, err := GetVideoInfo(outPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Width != 31 || info.Height != 17 {
+			t.Errorf("%s: unexpected size %dx%d", name, info.Width, info.Height)
+		}
+	}
+}
+
+func TestAnimationEncoding(t *testing.T) {
+	encoding, err := animationEncoding("out.GIF", &AnimationOptions{
+		Loops:      1,
+		Dither:     DitherBayer,
+		BayerScale: 3,
+		MaxColors:  64,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"-c:v", "gif",
+		"-filter:v", "split[frames][palette_in];" +
+			"[palette_in]palettegen=max_colors=64:stats_mode=full[palette];" +
+			"[frames][palette]paletteuse=dither=bayer:bayer_scale=3",
+		"-loop", "-1", "-f", "gif",
+	}
+	if actual := encoding.flags(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+
+	encoding, err = animationEncoding("out.webp", &AnimationOptions{Lossless: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"-c:v", "libwebp", "-lossless", "1", "-quality", "75", "-loop", "0",
+		"-f", "webp"}
+	if actual := encoding.flags(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+
+	if _, err := animationEncoding("out.mp4", &AnimationOptions{}); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+	if _, err := animationEncoding("out.gif", &AnimationOptions{MaxColors: 1000}); err == nil {
+		t.Error("expected error for invalid max colors")
+	}
+}
@@ -1,7 +1,6 @@
 package ffmpego
 
 import (
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -13,47 +12,109 @@ import (
 type AudioInfo struct {
 	// Frequency stores the frequency in Hz.
 	Frequency int
+
+	// Channels is the number of audio channels.
+	Channels int
+
+	// ChannelLayout is the name of ffmpeg's channel layout,
+	// such as "stereo" or "5.1(side)". It is empty if the
+	// channels have no known layout.
+	ChannelLayout string
 }
 
 // GetAudioInfo gets information about a audio file.
 func GetAudioInfo(path string) (info *AudioInfo, err error) {
+	return GetAudioInfoStream(path, nil)
+}
+
+// GetAudioInfoStream gets information about an audio
+// stream in a file.
+//
+// If selector is nil, the first audio stream is used.
+func GetAudioInfoStream(path string, selector StreamSelector) (info *AudioInfo, err error) {
 	defer func() {
 		if err != nil {
 			err = errors.Wrap(err, "get audio info")
 		}
 	}()
 
-	// Make sure file exists so we can give a clean error
-	// message in this case, instead of depending on ffmpeg.
-	if _, err := os.Stat(path); err != nil {
-		return nil, err
-	}
+	_, info, err = getAudioStream(path, selector)
+	return info, err
+}
 
-	lines, err := infoOutputLines(path)
+func getAudioStream(path string, selector StreamSelector) (*StreamInfo, *AudioInfo, error) {
+	mediaInfo, err := getMediaInfo(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	stream, err := mediaInfo.selectStream(StreamTypeAudio, selector)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	if stream.Audio.Frequency == 0 {
+		return nil, nil, errors.New("could not find frequency in output")
+	}
+	return stream, stream.Audio, nil
+}
 
-	var foundFreq bool
+// parseAudioStream parses the description of an audio
+// stream from ffmpeg's output.
+//
+// Values which are not found are left as zero.
+func parseAudioStream(desc string) (*AudioInfo, error) {
 	result := &AudioInfo{}
 
 	freqExp := regexp.MustCompilePOSIX(" ([0-9\\.]*) Hz,")
-	for _, line := range lines {
-		if !strings.Contains(line, "Audio:") {
-			continue
+	if match := freqExp.FindStringSubmatch(desc); match != nil {
+		freq, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, errors.Wrap(err, "parse frequency")
 		}
-		if match := freqExp.FindStringSubmatch(line); match != nil {
-			foundFreq = true
-			freq, err := strconv.Atoi(match[1])
-			if err != nil {
-				return nil, errors.Wrap(err, "parse frequency")
-			}
-			result.Frequency = freq
+		result.Frequency = freq
+	}
+	layoutExp := regexp.MustCompilePOSIX(" Hz, ([^,]+)")
+	if match := layoutExp.FindStringSubmatch(desc); match != nil {
+		layout := strings.TrimSpace(match[1])
+		if fields := strings.Fields(layout); len(fields) == 2 && fields[1] == "channels" {
+			// ffmpeg describes unknown layouts by their size.
+			result.Channels, _ = strconv.Atoi(fields[0])
+		} else {
+			result.ChannelLayout = layout
+			result.Channels = layoutChannels(layout)
 		}
 	}
 
-	if !foundFreq {
-		return nil, errors.New("could not find frequency in output")
-	}
 	return result, nil
 }
+
+// layoutChannels gets the number of channels in a named
+// channel layout, or 0 if the layout is not recognized.
+func layoutChannels(layout string) int {
+	if idx := strings.Index(layout, "("); idx >= 0 {
+		// Variants such as "5.1(side)" have the same size.
+		layout = layout[:idx]
+	}
+	named := map[string]int{
+		"mono":      1,
+		"stereo":    2,
+		"downmix":   2,
+		"quad":      4,
+		"hexagonal": 6,
+		"octagonal": 8,
+	}
+	if n, ok := named[layout]; ok {
+		return n
+	}
+	// Other layouts are named by their main and LFE
+	// channels, such as "5.1" or "7.1".
+	parts := strings.Split(layout, ".")
+	if len(parts) != 2 {
+		return 0
+	}
+	main, err1 := strconv.Atoi(parts[0])
+	lfe, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	return main + lfe
+}
@@ -14,3 +14,25 @@ func TestAudioInfo(t *testing.T) {
 		t.Errorf("expected frequency 8000 but got %d", info.Frequency)
 	}
 }
+
+func TestLayoutChannels(t *testing.T) {
+	for layout, expected := range map[string]int{
+		"mono":      1,
+		"stereo":    2,
+		"5.1(side)": 6,
+		"7.1":       8,
+		"quad":      4,
+		"unknown":   0,
+	} {
+		if actual := layoutChannels(layout); actual != expected {
+			t.Errorf("layout %s: expected %d channels but got %d", layout, expected, actual)
+		}
+	}
+	info, err := parseAudioStream("pcm_s16le, 48000 Hz, 3 channels, s16, 2304 kb/s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Channels != 3 || info.ChannelLayout != "" {
+		t.Errorf("unexpected info: %#v", info)
+	}
+}
@@ -3,6 +3,7 @@ package ffmpego
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"os/exec"
 	"strconv"
@@ -18,11 +19,7 @@ type AudioReader struct {
 }
 
 func NewAudioReader(path string) (*AudioReader, error) {
-	vr, err := newAudioReader(path, -1)
-	if err != nil {
-		err = errors.Wrap(err, "read audio")
-	}
-	return vr, err
+	return NewAudioReaderWithOptions(path, &AudioReaderOptions{})
 }
 
 // NewAudioReaderResampled creates an AudioReader that
@@ -31,20 +28,41 @@ func NewAudioReaderResampled(path string, frequency int) (*AudioReader, error) {
 	if frequency <= 0 {
 		panic("frequency must be positive")
 	}
-	vr, err := newAudioReader(path, frequency)
+	return NewAudioReaderWithOptions(path, &AudioReaderOptions{Frequency: frequency})
+}
+
+// AudioReaderOptions configures how an AudioReader decodes
+// a file.
+type AudioReaderOptions struct {
+	// Stream chooses which audio stream to decode.
+	// If nil, the first audio stream is used.
+	Stream StreamSelector
+
+	// Frequency, if non-zero, is the frequency to resample
+	// the audio to.
+	Frequency int
+}
+
+// NewAudioReaderWithOptions creates an AudioReader with
+// custom decoding options.
+func NewAudioReaderWithOptions(path string, opts *AudioReaderOptions) (*AudioReader, error) {
+	if opts.Frequency < 0 {
+		panic("frequency must not be negative")
+	}
+	vr, err := newAudioReader(path, opts)
 	if err != nil {
 		err = errors.Wrap(err, "read audio")
 	}
 	return vr, err
 }
 
-func newAudioReader(path string, forceFrequency int) (*AudioReader, error) {
-	info, err := GetAudioInfo(path)
+func newAudioReader(path string, opts *AudioReaderOptions) (*AudioReader, error) {
+	streamInfo, info, err := getAudioStream(path, opts.Stream)
 	if err != nil {
 		return nil, err
 	}
-	if forceFrequency > 0 {
-		info.Frequency = forceFrequency
+	if opts.Frequency > 0 {
+		info.Frequency = opts.Frequency
 	}
 
 	stream, err := CreateChildStream(true)
@@ -54,6 +72,7 @@ func newAudioReader(path string, forceFrequency int) (*AudioReader, error) {
 	cmd := exec.Command(
 		"ffmpeg",
 		"-i", path,
+		"-map", fmt.Sprintf("0:%d", streamInfo.Index),
 		"-f", "s16le",
 		"-ar", strconv.Itoa(info.Frequency),
 		"-ac", "1",
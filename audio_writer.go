@@ -3,7 +3,10 @@ package ffmpego
 import (
 	"encoding/binary"
 	"io"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 
 	"github.com/pkg/errors"
@@ -13,46 +16,153 @@ import (
 type AudioWriter struct {
 	command *exec.Cmd
 	writer  io.WriteCloser
+
+	// Only used for loudness normalization.
+	normalize *LoudnessTarget
+	encoding  *AudioEncoding
+	metadata  []string
+
+	// Input and output flags which add chapters to the
+	// output during normalization.
+	chapterInput []string
+	chapterFlags []string
+
+	tempDir    string
+	tempPath   string
+	outputPath string
+
+	// Temporary files which are removed once the writer is
+	// closed.
+	tempFiles []string
 }
 
 // NewAudioWriter creates a AudioWriter which is encoding
 // mono-channel audio to the given file.
 func NewAudioWriter(path string, frequency int) (*AudioWriter, error) {
-	vw, err := newAudioWriter(path, frequency)
+	return NewAudioWriterWithOptions(path, frequency, &AudioWriterOptions{})
+}
+
+// AudioWriterOptions configures how an AudioWriter encodes
+// a file.
+type AudioWriterOptions struct {
+	// Normalize, if non-nil, enables two-pass loudness
+	// normalization to the given target.
+	//
+	// When this is used, audio is first encoded to a
+	// temporary file, and the output file is only created
+	// once Close is called.
+	Normalize *LoudnessTarget
+
+	// Encoding configures the audio encoder.
+	// If nil, the encoder is chosen based on the file
+	// extension.
+	Encoding *AudioEncoding
+
+	// Metadata sets global tags of the output, such as
+	// "title" or "creation_time".
+	Metadata Metadata
+
+	// AudioMetadata sets tags of the audio stream, such as
+	// "language".
+	AudioMetadata Metadata
+
+	// Chapters, if non-empty, divides the output into named
+	// sections.
+	Chapters []*Chapter
+}
+
+// NewAudioWriterWithOptions creates an AudioWriter with
+// custom encoding options.
+func NewAudioWriterWithOptions(path string, frequency int, opts *AudioWriterOptions) (*AudioWriter, error) {
+	vw, err := newAudioWriter(path, frequency, opts)
 	if err != nil {
 		err = errors.Wrap(err, "write audio")
 	}
 	return vw, err
 }
 
-func newAudioWriter(path string, frequency int) (*AudioWriter, error) {
+func newAudioWriter(path string, frequency int, opts *AudioWriterOptions) (*AudioWriter, error) {
+	outputPath := path
+	var tempDir string
+	if opts.Normalize != nil {
+		var err error
+		tempDir, err = ioutil.TempDir("", "ffmpego-normalize")
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(tempDir, "audio.wav")
+	}
+
+	var tempFiles []string
+	var chapterInput, chapterFlags []string
+	if len(opts.Chapters) > 0 {
+		chapterFile, err := writeChapterFile(opts.Chapters)
+		if err != nil {
+			if tempDir != "" {
+				os.RemoveAll(tempDir)
+			}
+			return nil, err
+		}
+		tempFiles = append(tempFiles, chapterFile)
+		chapterInput, chapterFlags = chapterInputArgs(chapterFile, 1)
+	}
+	cleanup := func() {
+		if tempDir != "" {
+			os.RemoveAll(tempDir)
+		}
+		removeTempFiles(tempFiles)
+	}
+
 	stream, err := CreateChildStream(false)
 	if err != nil {
+		cleanup()
 		return nil, err
 	}
-	cmd := exec.Command(
-		"ffmpeg",
+	flags := []string{
 		"-y",
 		// Audio format
 		"-ar", strconv.Itoa(frequency), "-ac", "1", "-f", "s16le",
 		// Audio parameters
 		"-probesize", "32", "-thread_queue_size", "60", "-i", stream.ResourceURL(),
-		// Output parameters
-		"-pix_fmt", "yuv420p", path,
-	)
+	}
+	metadataFlags := outputMetadataFlags(opts.Metadata, nil, opts.AudioMetadata)
+	if opts.Normalize == nil {
+		// When normalizing, the encoding, tags, and chapters
+		// are applied in the second pass instead.
+		flags = append(flags, chapterInput...)
+		flags = append(flags, chapterFlags...)
+		if opts.Encoding != nil {
+			flags = append(flags, opts.Encoding.flags()...)
+		}
+		flags = append(flags, metadataFlags...)
+	}
+	// Output parameters
+	flags = append(flags, "-pix_fmt", "yuv420p", path)
+	cmd := exec.Command("ffmpeg", flags...)
 	cmd.ExtraFiles = stream.ExtraFiles()
 	if err := cmd.Start(); err != nil {
 		stream.Cancel()
+		cleanup()
 		return nil, err
 	}
 	writer, err := stream.Connect()
 	if err != nil {
 		cmd.Process.Kill()
+		cleanup()
 		return nil, err
 	}
 	return &AudioWriter{
-		command: cmd,
-		writer:  writer,
+		command:      cmd,
+		writer:       writer,
+		normalize:    opts.Normalize,
+		encoding:     opts.Encoding,
+		metadata:     metadataFlags,
+		chapterInput: chapterInput,
+		chapterFlags: chapterFlags,
+		tempDir:      tempDir,
+		tempPath:     path,
+		outputPath:   outputPath,
+		tempFiles:    tempFiles,
 	}, nil
 }
 
@@ -73,10 +183,23 @@ func (v *AudioWriter) WriteSamples(samples []float64) error {
 // Close closes the audio file and waits for encoding to
 // complete.
 func (v *AudioWriter) Close() error {
+	if v.tempDir != "" {
+		defer os.RemoveAll(v.tempDir)
+	}
+	defer removeTempFiles(v.tempFiles)
 	v.writer.Close()
 	err := v.command.Wait()
 	if err != nil {
 		return errors.Wrap(err, "close audio writer")
 	}
+	if v.normalize != nil {
+		// Chapters are taken from the input after the
+		// temporary audio file, as in the first pass.
+		flags := append(append([]string{}, v.chapterFlags...), v.metadata...)
+		if err := normalizeLoudness(v.tempPath, v.outputPath, v.normalize, v.encoding,
+			v.chapterInput, flags); err != nil {
+			return errors.Wrap(err, "close audio writer")
+		}
+	}
 	return nil
 }
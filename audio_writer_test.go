@@ -37,3 +37,36 @@ func TestAudioWriter(t *testing.T) {
 		t.Fatal("stat output file should work but got:", err)
 	}
 }
+
+func TestAudioWriterNormalized(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-audio-writer-normalized")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	outPath := filepath.Join(dir, "out.wav")
+	aw, err := NewAudioWriterWithOptions(outPath, 44100, &AudioWriterOptions{
+		Normalize: &LoudnessTarget{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := make([]float64, 44100*3)
+	for t := range samples {
+		samples[t] = 0.01 * math.Sin(math.Pi*2*400*float64(t)/44100)
+	}
+	if err := aw.WriteSamples(samples); err != nil {
+		aw.Close()
+		t.Fatal(err)
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	loudness, err := MeasureLoudness(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(loudness.Integrated+23) > 1 {
+		t.Errorf("expected loudness of -23 LUFS but got %f", loudness.Integrated)
+	}
+}
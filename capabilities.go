@@ -0,0 +1,78 @@
+package ffmpego
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var capabilities struct {
+	lock     sync.Mutex
+	filters  map[string]bool
+	encoders map[string]bool
+}
+
+// HasFilter checks if the installed version of ffmpeg
+// supports a filter, such as "libvmaf".
+//
+// The list of filters is only loaded once it has been
+// read successfully, so this is cheap to call repeatedly.
+func HasFilter(name string) (bool, error) {
+	capabilities.lock.Lock()
+	defer capabilities.lock.Unlock()
+	if capabilities.filters == nil {
+		filters, err := listCapabilities("-filters")
+		if err != nil {
+			return false, errors.Wrap(err, "list filters")
+		}
+		capabilities.filters = filters
+	}
+	return capabilities.filters[name], nil
+}
+
+// HasEncoder checks if the installed version of ffmpeg
+// supports an encoder, such as "libx264".
+//
+// The list of encoders is only loaded once it has been
+// read successfully, so this is cheap to call repeatedly.
+func HasEncoder(name string) (bool, error) {
+	capabilities.lock.Lock()
+	defer capabilities.lock.Unlock()
+	if capabilities.encoders == nil {
+		encoders, err := listCapabilities("-encoders")
+		if err != nil {
+			return false, errors.Wrap(err, "list encoders")
+		}
+		capabilities.encoders = encoders
+	}
+	return capabilities.encoders[name], nil
+}
+
+// listCapabilities parses a listing like the one printed
+// by "ffmpeg -filters", in which each entry is a line with
+// flags followed by a name.
+func listCapabilities(flag string) (map[string]bool, error) {
+	lines, err := runFFmpegStdout(context.Background(), "-hide_banner", flag)
+	if err != nil {
+		return nil, err
+	}
+	return parseCapabilities(lines), nil
+}
+
+func parseCapabilities(lines []string) map[string]bool {
+	result := map[string]bool{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		// Skip headers and legends like " V..... = Video".
+		if len(fields) < 3 || fields[1] == "=" {
+			continue
+		}
+		if strings.Trim(fields[0], "ABCDEFGHIJKLMNOPQRSTUVWXYZ.|") != "" {
+			continue
+		}
+		result[fields[1]] = true
+	}
+	return result
+}
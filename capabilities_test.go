@@ -0,0 +1,49 @@
+package ffmpego
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHasEncoder(t *testing.T) {
+	if ok, err := HasEncoder("libx264"); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Error("expected libx264 encoder to be available")
+	}
+	if ok, err := HasEncoder("not_a_real_encoder"); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Error("unexpected encoder")
+	}
+}
+
+func TestParseCapabilities(t *testing.T) {
+	output := `Filters:
+  T.. = Timeline support
+  .S. = Slice threading
+  ..C = Command support
+  A = Audio input/output
+  V = Video input/output
+  N = Dynamic number and/or type of input/output
+  | = Source or sink filter
+ ... abench            A->A       Benchmark part of a filtergraph.
+ TSC psnr              VV->V      Calculate the PSNR between two video streams.
+ TS. ssim              VV->V      Calculate the SSIM between two video streams.`
+	encoders := `Encoders:
+ V..... = Video
+ A..... = Audio
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ A....D aac                  AAC (Advanced Audio Coding)`
+
+	caps := parseCapabilities(strings.Split(encoders, "\n"))
+	if !caps["libx264"] || !caps["aac"] || len(caps) != 2 {
+		t.Errorf("unexpected encoders: %v", caps)
+	}
+
+	caps = parseCapabilities(strings.Split(output, "\n"))
+	if !caps["psnr"] || !caps["ssim"] {
+		t.Errorf("unexpected filters: %v", caps)
+	}
+}
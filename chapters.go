@@ -0,0 +1,80 @@
+package ffmpego
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A Chapter is a named section of a media file.
+type Chapter struct {
+	Start time.Duration
+	End   time.Duration
+	Title string
+}
+
+// writeChapterFile writes chapters to a temporary file in
+// ffmpeg's FFMETADATA format, which the caller must remove.
+func writeChapterFile(chapters []*Chapter) (path string, err error) {
+	for _, c := range chapters {
+		if c.End <= c.Start {
+			return "", errors.Errorf("chapter %q must end after it starts", c.Title)
+		}
+	}
+	f, err := ioutil.TempFile("", "ffmpego-chapters*.txt")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(encodeChapterMetadata(chapters))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// encodeChapterMetadata creates an FFMETADATA file which
+// only contains chapters.
+func encodeChapterMetadata(chapters []*Chapter) string {
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for _, c := range chapters {
+		b.WriteString("[CHAPTER]\nTIMEBASE=1/1000000\n")
+		fmt.Fprintf(&b, "START=%d\nEND=%d\n", c.Start/time.Microsecond, c.End/time.Microsecond)
+		if c.Title != "" {
+			b.WriteString("title=" + escapeMetadataValue(c.Title) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// escapeMetadataValue escapes the characters which have a
+// special meaning in an FFMETADATA file.
+func escapeMetadataValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n").Replace(value)
+}
+
+// removeTempFiles removes temporary files which were
+// created for a command.
+func removeTempFiles(paths []string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
+
+// chapterInputArgs creates the input flags for a chapter
+// file, as well as the output flags that take chapters from
+// it, given the index of the chapter input.
+func chapterInputArgs(path string, inputIndex int) (input, output []string) {
+	input = []string{"-f", "ffmetadata", "-i", path}
+	output = []string{"-map_chapters", strconv.Itoa(inputIndex)}
+	return
+}
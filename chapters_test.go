@@ -0,0 +1,166 @@
+package ffmpego
+
+import (
+	"context"
+	"image"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testChaptersOutput = `Input #0, matroska,webm, from 'lecture.mkv':
+  Metadata:
+    ENCODER         : Lavf58.29.100
+  Duration: 00:00:02.00, start: 0.000000, bitrate: 31 kb/s
+  Chapters:
+    Chapter #0:0: start 0.000000, end 0.500000
+      Metadata:
+        title           : Intro
+    Chapter #0:1: start 0.500000, end 2.000000
+  Stream #0:0: Video: h264 (High), yuv420p(progressive), 64x32, 12 fps, 12 tbr, 1k tbn (default)
+    Metadata:
+      title           : Camera
+At least one output file must be specified`
+
+func TestParseChapters(t *testing.T) {
+	info, err := parseMediaInfo(strings.Split(testChaptersOutput, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Chapter{
+		{Start: 0, End: 500 * time.Millisecond, Title: "Intro"},
+		{Start: 500 * time.Millisecond, End: 2 * time.Second},
+	}
+	if len(info.Chapters) != len(expected) {
+		t.Fatalf("expected %d chapters but got %d", len(expected), len(info.Chapters))
+	}
+	for i, c := range info.Chapters {
+		if *c != expected[i] {
+			t.Errorf("chapter %d: expected %+v but got %+v", i, expected[i], *c)
+		}
+	}
+	if info.Metadata["ENCODER"] != "Lavf58.29.100" || info.Metadata["title"] != "" {
+		t.Errorf("unexpected global metadata: %v", info.Metadata)
+	}
+	if len(info.Streams) != 1 || info.Streams[0].Metadata["title"] != "Camera" {
+		t.Errorf("unexpected streams: %v", info.Streams)
+	}
+}
+
+func TestEncodeChapterMetadata(t *testing.T) {
+	actual := encodeChapterMetadata([]*Chapter{
+		{Start: 0, End: 1500 * time.Millisecond, Title: "Part 1; a=b"},
+		{Start: 1500 * time.Millisecond, End: 3 * time.Second},
+	})
+	expected := ";FFMETADATA1\n" +
+		"[CHAPTER]\nTIMEBASE=1/1000000\nSTART=0\nEND=1500000\ntitle=Part 1\\; a\\=b\n" +
+		"[CHAPTER]\nTIMEBASE=1/1000000\nSTART=1500000\nEND=3000000\n"
+	if actual != expected {
+		t.Errorf("unexpected metadata file:\n%s", actual)
+	}
+
+	if _, err := writeChapterFile([]*Chapter{{Start: time.Second, End: time.Second}}); err == nil {
+		t.Error("expected error for empty chapter")
+	}
+}
+
+func TestVideoWriterChapters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-video-writer-chapters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chapters := []*Chapter{
+		{Start: 0, End: time.Second, Title: "First"},
+		{Start: time.Second, End: 2 * time.Second, Title: "Second"},
+	}
+	outPath := filepath.Join(dir, "out.mkv")
+	vw, err := NewVideoWriterWithOptions(outPath, 50, 50, 12, &VideoWriterOptions{
+		Chapters: chapters,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 24; i++ {
+		if err := vw.WriteFrame(image.NewGray(image.Rect(0, 0, 50, 50))); err != nil {
+			vw.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := vw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkChapters(t, outPath, chapters)
+
+	// Chapters should be copied or replaced by Transcode.
+	copyPath := filepath.Join(dir, "copy.mp4")
+	if err := Transcode(context.Background(), outPath, copyPath, &TranscodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	checkChapters(t, copyPath, chapters)
+
+	replaced := []*Chapter{{Start: 0, End: 2 * time.Second, Title: "Only"}}
+	replacedPath := filepath.Join(dir, "replaced.mp4")
+	err = Transcode(context.Background(), outPath, replacedPath, &TranscodeOptions{
+		Chapters: replaced,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkChapters(t, replacedPath, replaced)
+}
+
+func checkChapters(t *testing.T, path string, expected []*Chapter) {
+	info, err := GetMediaInfo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Chapters) != len(expected) {
+		t.Fatalf("%s: expected %d chapters but got %d", path, len(expected), len(info.Chapters))
+	}
+	for i, c := range info.Chapters {
+		if c.Title != expected[i].Title || c.Start != expected[i].Start {
+			t.Errorf("%s: chapter %d: expected %+v but got %+v", path, i, *expected[i], *c)
+		}
+	}
+}
+
+func TestAudioWriterChapters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-audio-writer-chapters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chapters := []*Chapter{
+		{Start: 0, End: time.Second, Title: "First"},
+		{Start: time.Second, End: 2 * time.Second, Title: "Second"},
+	}
+	for _, normalize := range []*LoudnessTarget{nil, {}} {
+		outPath := filepath.Join(dir, "out.m4a")
+		aw, err := NewAudioWriterWithOptions(outPath, 8000, &AudioWriterOptions{
+			Normalize: normalize,
+			Chapters:  chapters,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples := make([]float64, 16000)
+		for i := range samples {
+			samples[i] = 0.5 * math.Sin(float64(i)*0.1)
+		}
+		if err := aw.WriteSamples(samples); err != nil {
+			aw.Close()
+			t.Fatal(err)
+		}
+		if err := aw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		checkChapters(t, outPath, chapters)
+	}
+}
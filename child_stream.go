@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"runtime"
+	"strconv"
 	"time"
 )
 
@@ -62,6 +63,33 @@ func CreateChildStream(reading bool) (ChildStream, error) {
 	}
 }
 
+// createChildStreams creates multiple ChildStreams for the
+// same command, with one reading flag per stream.
+//
+// The ExtraFiles() of every stream should be passed to the
+// command in order.
+func createChildStreams(reading ...bool) ([]ChildStream, error) {
+	var streams []ChildStream
+	numFiles := 0
+	for _, r := range reading {
+		stream, err := CreateChildStream(r)
+		if err != nil {
+			for _, s := range streams {
+				s.Cancel()
+			}
+			return nil, err
+		}
+		if pipe, ok := stream.(*ChildPipeStream); ok {
+			// Extra files are numbered after stdin, stdout, and
+			// stderr.
+			pipe.fd = 3 + numFiles
+		}
+		numFiles += len(stream.ExtraFiles())
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
+
 // A ChildPipeStream uses a pipe to communicate with
 // subprocesses.
 //
@@ -69,6 +97,7 @@ func CreateChildStream(reading bool) (ChildStream, error) {
 type ChildPipeStream struct {
 	parentPipe *os.File
 	childPipe  *os.File
+	fd         int
 }
 
 // NewChildPipeStream creates a ChildPipeStream.
@@ -84,11 +113,13 @@ func NewChildPipeStream(reading bool) (*ChildPipeStream, error) {
 		return &ChildPipeStream{
 			parentPipe: reader,
 			childPipe:  writer,
+			fd:         3,
 		}, nil
 	} else {
 		return &ChildPipeStream{
 			parentPipe: writer,
 			childPipe:  reader,
+			fd:         3,
 		}, nil
 	}
 }
@@ -98,7 +129,7 @@ func (c *ChildPipeStream) ExtraFiles() []*os.File {
 }
 
 func (c *ChildPipeStream) ResourceURL() string {
-	return "pipe:3"
+	return "pipe:" + strconv.Itoa(c.fd)
 }
 
 func (c *ChildPipeStream) Connect() (io.ReadWriteCloser, error) {
@@ -0,0 +1,138 @@
+package ffmpego
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// runFFmpeg runs ffmpeg to completion and returns the lines
+// it logged.
+//
+// If ffmpeg fails, the returned error includes the last
+// line of its log, which typically describes the problem.
+func runFFmpeg(ctx context.Context, args ...string) ([]string, error) {
+	var output bytes.Buffer
+	err := runFFmpegOutput(ctx, &output, &output, args...)
+	return splitLines(output.String()), err
+}
+
+// runFFmpegStdout is like runFFmpeg, but returns the
+// standard output of the command separately from the log.
+func runFFmpegStdout(ctx context.Context, args ...string) ([]string, error) {
+	var stdout, stderr bytes.Buffer
+	err := runFFmpegOutput(ctx, &stdout, &stderr, args...)
+	return splitLines(stdout.String()), err
+}
+
+func runFFmpegOutput(ctx context.Context, stdout, stderr *bytes.Buffer, args ...string) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg", append([]string{"-nostdin"}, args...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if err != nil {
+		return ffmpegError(ctx, err, stderr.String())
+	}
+	return nil
+}
+
+// runFFmpegProgress is like runFFmpeg, but also passes the
+// progress updates from ffmpeg to f as they are logged.
+//
+// Each update is a set of key-value pairs, such as
+// "frame" and "out_time_us".
+func runFFmpegProgress(ctx context.Context, f func(map[string]string), args ...string) ([]string, error) {
+	stream, err := CreateChildStream(true)
+	if err != nil {
+		return nil, err
+	}
+	var output bytes.Buffer
+	flags := append([]string{"-nostdin", "-progress", stream.ResourceURL(), "-nostats"}, args...)
+	cmd := exec.CommandContext(ctx, "ffmpeg", flags...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.ExtraFiles = stream.ExtraFiles()
+	if err := cmd.Start(); err != nil {
+		stream.Cancel()
+		return nil, err
+	}
+	reader, err := stream.Connect()
+	if err != nil {
+		cmd.Process.Kill()
+		if waitErr := cmd.Wait(); waitErr != nil {
+			err = waitErr
+		}
+		return nil, ffmpegError(ctx, err, output.String())
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		parseProgress(reader, f)
+	}()
+	err = cmd.Wait()
+	<-done
+	reader.Close()
+	if err != nil {
+		return nil, ffmpegError(ctx, err, output.String())
+	}
+	return splitLines(output.String()), nil
+}
+
+// parseProgress reads the output of the -progress flag
+// until EOF.
+func parseProgress(r io.Reader, f func(map[string]string)) {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		values[parts[0]] = strings.TrimSpace(parts[1])
+		if parts[0] == "progress" {
+			f(values)
+			values = map[string]string{}
+		}
+	}
+	// Make sure the pipe is drained even if the scanner
+	// hits a very long line.
+	io.Copy(ioutil.Discard, r)
+}
+
+// ffmpegError creates an error for a failed ffmpeg command
+// from its log output.
+func ffmpegError(ctx context.Context, err error, log string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	lines := splitLines(log)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return errors.Wrapf(err, "ffmpeg: %s", line)
+		}
+	}
+	return err
+}
+
+func splitLines(output string) []string {
+	return strings.Split(strings.ReplaceAll(output, "\r", "\n"), "\n")
+}
+
+// escapeFilterValue escapes a string so that it can be
+// used as an option value in a filtergraph, such as a file
+// path.
+//
+// Values are escaped once for the filter's options, and
+// once more for the filtergraph itself.
+func escapeFilterValue(value string) string {
+	optionEscaper := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`)
+	graphEscaper := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`,
+		`;`, `\;`)
+	return graphEscaper.Replace(optionEscaper.Replace(value))
+}
@@ -0,0 +1,261 @@
+package ffmpego
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ConcatOptions configures how Concat joins files.
+type ConcatOptions struct {
+	// Width, Height, and FPS are the video parameters of the
+	// output if the inputs must be re-encoded.
+	// If 0, they are taken from the first input.
+	//
+	// Inputs with a different aspect ratio are scaled to fit
+	// and padded with black bars.
+	Width  int
+	Height int
+	FPS    float64
+
+	// Frequency is the audio sample rate of the output if
+	// the inputs must be re-encoded.
+	// If 0, it is taken from the first input with audio.
+	Frequency int
+
+	// Video and Audio configure the encoders if the inputs
+	// must be re-encoded. If nil, DefaultVideoEncoding() and
+	// the default audio encoder for the output format are
+	// used.
+	Video *VideoEncoding
+	Audio *AudioEncoding
+
+	// Reencode forces the inputs to be re-encoded, even if
+	// they could be joined with stream copy.
+	Reencode bool
+}
+
+// Concat joins media files one after another.
+//
+// If every input has the same codecs and parameters, and
+// these match the options, the files are joined without
+// re-encoding them. Otherwise, the inputs are converted to
+// a common size, frame rate, and sample rate, and the
+// result is re-encoded.
+//
+// Only the first video and audio stream of each input is
+// used. If some inputs have audio and others do not, the
+// latter are joined with silence.
+func Concat(ctx context.Context, inputs []string, output string, opts *ConcatOptions) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "concatenate")
+		}
+	}()
+	if len(inputs) == 0 {
+		return errors.New("no inputs")
+	}
+
+	var clips []*concatClip
+	for _, input := range inputs {
+		clip, err := probeConcatClip(input)
+		if err != nil {
+			return err
+		}
+		clips = append(clips, clip)
+	}
+
+	if !opts.Reencode && concatCompatible(clips, opts) {
+		return concatCopy(ctx, inputs, output)
+	}
+
+	target := concatTarget(clips, opts)
+	args := []string{"-y"}
+	for _, input := range inputs {
+		args = append(args, "-i", input)
+	}
+	args = append(args, "-filter_complex", concatFilter(clips, target), "-map", "[v]")
+	args = append(args, target.Video.flags()...)
+	if target.Frequency != 0 {
+		args = append(args, "-map", "[a]")
+		if opts.Audio != nil {
+			args = append(args, opts.Audio.flags()...)
+		}
+	}
+	args = append(args, output)
+	_, err = runFFmpeg(ctx, args...)
+	return err
+}
+
+// concatCopy joins files using the concat demuxer.
+func concatCopy(ctx context.Context, inputs []string, output string) error {
+	tempDir, err := ioutil.TempDir("", "ffmpego-concat")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	listPath := filepath.Join(tempDir, "inputs.txt")
+	if err := writeConcatList(listPath, inputs); err != nil {
+		return err
+	}
+	_, err = runFFmpeg(
+		ctx,
+		"-y", "-f", "concat", "-safe", "0", "-i", listPath,
+		"-map", "0:v:0", "-map", "0:a:0?", "-c", "copy", output,
+	)
+	return err
+}
+
+// A concatClip summarizes the streams of an input to
+// Concat.
+type concatClip struct {
+	Info  *MediaInfo
+	Video *StreamInfo
+
+	// Audio is nil if the input has no audio.
+	Audio *StreamInfo
+}
+
+func probeConcatClip(path string) (*concatClip, error) {
+	info, err := getMediaInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	video, err := info.selectStream(StreamTypeVideo, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+	audio, _ := selectOptionalStream(info, StreamTypeAudio, nil)
+	return &concatClip{Info: info, Video: video, Audio: audio}, nil
+}
+
+// concatCompatible checks if clips can be joined with the
+// concat demuxer and stream copy.
+func concatCompatible(clips []*concatClip, opts *ConcatOptions) bool {
+	first := clips[0]
+	v := first.Video.Video
+	if (opts.Width != 0 && opts.Width != v.Width) ||
+		(opts.Height != 0 && opts.Height != v.Height) ||
+		(opts.FPS != 0 && opts.FPS != v.FPS) {
+		return false
+	}
+	if opts.Frequency != 0 && first.Audio != nil && opts.Frequency != first.Audio.Audio.Frequency {
+		return false
+	}
+	for _, clip := range clips[1:] {
+		// Stream copy requires identical parameters, down to
+		// the pixel format, time base, and channel layout.
+		if clip.Video.Codec != first.Video.Codec || clip.Video.Profile != first.Video.Profile ||
+			*clip.Video.Video != *v {
+			return false
+		}
+		if (clip.Audio == nil) != (first.Audio == nil) {
+			return false
+		}
+		if clip.Audio != nil && (clip.Audio.Codec != first.Audio.Codec ||
+			clip.Audio.Profile != first.Audio.Profile ||
+			*clip.Audio.Audio != *first.Audio.Audio) {
+			return false
+		}
+	}
+	return true
+}
+
+type concatTargetParams struct {
+	Width     int
+	Height    int
+	FPS       float64
+	Video     *VideoEncoding
+	Frequency int
+}
+
+// concatTarget decides on the output parameters when
+// clips must be re-encoded.
+//
+// The resulting Frequency is 0 if no clip has audio.
+func concatTarget(clips []*concatClip, opts *ConcatOptions) *concatTargetParams {
+	first := clips[0].Video.Video
+	target := &concatTargetParams{
+		Width:     opts.Width,
+		Height:    opts.Height,
+		FPS:       opts.FPS,
+		Video:     opts.Video,
+		Frequency: opts.Frequency,
+	}
+	if target.Width == 0 {
+		target.Width = first.Width
+	}
+	if target.Height == 0 {
+		target.Height = first.Height
+	}
+	if target.FPS == 0 {
+		target.FPS = first.FPS
+	}
+	if target.Video == nil {
+		target.Video = DefaultVideoEncoding()
+	}
+	if target.Video.needsEvenSize() {
+		target.Width += target.Width % 2
+		target.Height += target.Height % 2
+	}
+
+	hasAudio := false
+	for _, clip := range clips {
+		if clip.Audio != nil {
+			hasAudio = true
+			if target.Frequency == 0 {
+				target.Frequency = clip.Audio.Audio.Frequency
+			}
+		}
+	}
+	if !hasAudio {
+		target.Frequency = 0
+	}
+	return target
+}
+
+// concatFilter creates a filtergraph which converts every
+// clip to the target parameters and joins them, producing
+// the outputs [v] and, if there is audio, [a].
+func concatFilter(clips []*concatClip, target *concatTargetParams) string {
+	var parts []string
+	var concatInputs string
+	for i, clip := range clips {
+		parts = append(parts, fmt.Sprintf(
+			"[%d:%d]scale=%d:%d:force_original_aspect_ratio=decrease,"+
+				"pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%f[v%d]",
+			i, clip.Video.Index, target.Width, target.Height,
+			target.Width, target.Height, target.FPS, i,
+		))
+		concatInputs += fmt.Sprintf("[v%d]", i)
+		if target.Frequency == 0 {
+			continue
+		}
+		if clip.Audio != nil {
+			parts = append(parts, fmt.Sprintf(
+				"[%d:%d]aresample=%d[a%d]", i, clip.Audio.Index, target.Frequency, i,
+			))
+		} else {
+			parts = append(parts, fmt.Sprintf(
+				"anullsrc=r=%d,atrim=duration=%s[a%d]",
+				target.Frequency, formatSeconds(clip.Info.Duration), i,
+			))
+		}
+		concatInputs += fmt.Sprintf("[a%d]", i)
+	}
+	numAudio := 0
+	outputs := "[v]"
+	if target.Frequency != 0 {
+		numAudio = 1
+		outputs += "[a]"
+	}
+	parts = append(parts, fmt.Sprintf(
+		"%sconcat=n=%d:v=1:a=%d%s", concatInputs, len(clips), numAudio, outputs,
+	))
+	return strings.Join(parts, ";")
+}
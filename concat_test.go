@@ -0,0 +1,145 @@
+package ffmpego
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConcat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-concat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inPath := filepath.Join("test_data", "test_video.mp4")
+	smallPath := filepath.Join(dir, "small.mp4")
+	err = Transcode(context.Background(), inPath, smallPath, &TranscodeOptions{
+		Width: 32,
+		FPS:   24,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, inputs := range [][]string{{inPath, inPath}, {inPath, smallPath}} {
+		outPath := filepath.Join(dir, "out.mp4")
+		if err := Concat(context.Background(), inputs, outPath, &ConcatOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		info, err := GetMediaInfo(outPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Duration < 3900*time.Millisecond || info.Duration > 4100*time.Millisecond {
+			t.Errorf("unexpected duration: %v", info.Duration)
+		}
+		video := info.Streams[0].Video
+		if video.Width != 64 || video.Height != 32 || video.FPS != 12 {
+			t.Errorf("unexpected video info: %#v", video)
+		}
+	}
+}
+
+func TestConcatCompatible(t *testing.T) {
+	clip := func(width int, fps float64, frequency int) *concatClip {
+		c := &concatClip{
+			Info: &MediaInfo{Duration: 2 * time.Second},
+			Video: &StreamInfo{
+				Index:   0,
+				Codec:   "h264",
+				Profile: "High",
+				Video: &VideoInfo{Width: width, Height: 32, FPS: fps, PixelFormat: "yuv420p",
+					TimeScale: 12288},
+			},
+		}
+		if frequency != 0 {
+			c.Audio = &StreamInfo{Index: 1, Codec: "aac", Audio: &AudioInfo{
+				Frequency:     frequency,
+				Channels:      2,
+				ChannelLayout: "stereo",
+			}}
+		}
+		return c
+	}
+	modified := func(f func(c *concatClip)) *concatClip {
+		c := clip(64, 12, 8000)
+		f(c)
+		return c
+	}
+
+	tests := []struct {
+		Clips    []*concatClip
+		Options  ConcatOptions
+		Expected bool
+	}{
+		{[]*concatClip{clip(64, 12, 8000), clip(64, 12, 8000)}, ConcatOptions{}, true},
+		{[]*concatClip{clip(64, 12, 8000), clip(64, 12, 8000)}, ConcatOptions{FPS: 12}, true},
+		{[]*concatClip{clip(64, 12, 8000), clip(64, 12, 8000)}, ConcatOptions{FPS: 24}, false},
+		{[]*concatClip{clip(64, 12, 8000), clip(32, 12, 8000)}, ConcatOptions{}, false},
+		{[]*concatClip{clip(64, 12, 8000), clip(64, 24, 8000)}, ConcatOptions{}, false},
+		{[]*concatClip{clip(64, 12, 8000), clip(64, 12, 44100)}, ConcatOptions{}, false},
+		{[]*concatClip{clip(64, 12, 8000), clip(64, 12, 0)}, ConcatOptions{}, false},
+		{
+			[]*concatClip{clip(64, 12, 8000), modified(func(c *concatClip) {
+				c.Video.Video.PixelFormat = "yuv444p"
+			})},
+			ConcatOptions{},
+			false,
+		},
+		{
+			[]*concatClip{clip(64, 12, 8000), modified(func(c *concatClip) {
+				c.Video.Video.TimeScale = 90000
+			})},
+			ConcatOptions{},
+			false,
+		},
+		{
+			[]*concatClip{clip(64, 12, 8000), modified(func(c *concatClip) {
+				c.Video.Profile = "Main"
+			})},
+			ConcatOptions{},
+			false,
+		},
+		{
+			[]*concatClip{clip(64, 12, 8000), modified(func(c *concatClip) {
+				c.Audio.Audio.Channels = 1
+				c.Audio.Audio.ChannelLayout = "mono"
+			})},
+			ConcatOptions{},
+			false,
+		},
+		{
+			[]*concatClip{clip(64, 12, 8000), modified(func(c *concatClip) {
+				c.Audio.Audio.ChannelLayout = "downmix"
+			})},
+			ConcatOptions{},
+			false,
+		},
+	}
+	for i, test := range tests {
+		if actual := concatCompatible(test.Clips, &test.Options); actual != test.Expected {
+			t.Errorf("test %d: expected %v but got %v", i, test.Expected, actual)
+		}
+	}
+
+	clips := []*concatClip{clip(63, 12, 0), clip(32, 24, 8000)}
+	target := concatTarget(clips, &ConcatOptions{FPS: 30})
+	if target.Width != 64 || target.Height != 32 || target.FPS != 30 || target.Frequency != 8000 {
+		t.Errorf("unexpected target: %#v", target)
+	}
+	expected := "[0:0]scale=64:32:force_original_aspect_ratio=decrease," +
+		"pad=64:32:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30.000000[v0];" +
+		"anullsrc=r=8000,atrim=duration=2.000000[a0];" +
+		"[1:0]scale=64:32:force_original_aspect_ratio=decrease," +
+		"pad=64:32:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30.000000[v1];" +
+		"[1:1]aresample=8000[a1];" +
+		"[v0][a0][v1][a1]concat=n=2:v=1:a=1[v][a]"
+	if actual := concatFilter(clips, target); actual != expected {
+		t.Errorf("expected filter:\n%s\ngot:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,397 @@
+package ffmpego
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ContactSheetOptions configures the layout of a contact
+// sheet.
+type ContactSheetOptions struct {
+	// Rows and Columns determine the number of tiles.
+	// If 0, 4 is used for each.
+	Rows    int
+	Columns int
+
+	// TileWidth and TileHeight are the dimensions of each
+	// tile. If either is 0, it is computed from the other
+	// using the aspect ratio of the video. If both are 0,
+	// TileWidth is 160.
+	TileWidth  int
+	TileHeight int
+
+	// Spacing is the number of pixels between tiles and
+	// around the edges of the sheet.
+	Spacing int
+
+	// Background is the color behind the tiles.
+	// If nil, black is used.
+	Background color.Color
+
+	// Labels, if true, draws the timestamp of each tile in
+	// its corner.
+	Labels bool
+}
+
+// ContactSheet creates a grid of thumbnails which are
+// evenly spaced throughout a video.
+func ContactSheet(path string, opts *ContactSheetOptions) (sheet image.Image, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "create contact sheet")
+		}
+	}()
+
+	rows, cols := opts.Rows, opts.Columns
+	if rows == 0 {
+		rows = 4
+	}
+	if cols == 0 {
+		cols = 4
+	}
+	_, info, err := getVideoStream(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	tileWidth, tileHeight := tileSize(info, opts.TileWidth, opts.TileHeight)
+
+	frames, err := ExtractThumbnails(path, rows*cols)
+	if err != nil {
+		return nil, err
+	}
+
+	spacing := opts.Spacing
+	result := image.NewRGBA(image.Rect(
+		0, 0,
+		cols*(tileWidth+spacing)+spacing,
+		rows*(tileHeight+spacing)+spacing,
+	))
+	background := opts.Background
+	if background == nil {
+		background = color.Black
+	}
+	draw.Draw(result, result.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	for i, frame := range frames {
+		x := spacing + (i%cols)*(tileWidth+spacing)
+		y := spacing + (i/cols)*(tileHeight+spacing)
+		tileRect := image.Rect(x, y, x+tileWidth, y+tileHeight)
+		drawTile(result, tileRect, frame, opts.Labels)
+	}
+	return result, nil
+}
+
+// StoryboardOptions configures the sprite sheets of a
+// storyboard.
+type StoryboardOptions struct {
+	// Interval is the amount of time covered by each tile.
+	// If 0, 10 seconds is used.
+	Interval time.Duration
+
+	// Rows and Columns determine the maximum number of tiles
+	// in each sprite sheet. If 0, 10 is used for each.
+	Rows    int
+	Columns int
+
+	// TileWidth and TileHeight are the dimensions of each
+	// tile, as for ContactSheetOptions.
+	TileWidth  int
+	TileHeight int
+
+	// Labels, if true, draws the timestamp of each tile in
+	// its corner.
+	Labels bool
+}
+
+// A Storyboard is a set of sprite sheets summarizing a
+// video, along with a mapping from timestamps to tiles.
+type Storyboard struct {
+	Sheets []image.Image
+	Cues   []*StoryboardCue
+}
+
+// A StoryboardCue indicates which tile should be shown for
+// a range of time in a video.
+type StoryboardCue struct {
+	Start time.Duration
+	End   time.Duration
+
+	// Sheet is the index of the sprite sheet in the
+	// storyboard.
+	Sheet int
+
+	// Tile is the location of the tile in the sheet.
+	Tile image.Rectangle
+}
+
+// CreateStoryboard creates sprite sheets with one tile for
+// every interval of a video.
+func CreateStoryboard(path string, opts *StoryboardOptions) (storyboard *Storyboard, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "create storyboard")
+		}
+	}()
+
+	interval := opts.Interval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	rows, cols := opts.Rows, opts.Columns
+	if rows == 0 {
+		rows = 10
+	}
+	if cols == 0 {
+		cols = 10
+	}
+
+	mediaInfo, err := getMediaInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	if mediaInfo.Duration == 0 {
+		return nil, errors.New("unknown video duration")
+	}
+	videoStream, err := mediaInfo.selectStream(StreamTypeVideo, nil)
+	if err != nil {
+		return nil, err
+	}
+	tileWidth, tileHeight := tileSize(videoStream.Video, opts.TileWidth, opts.TileHeight)
+
+	// Every tile is decoded by a single process, which
+	// resamples the video to one frame per interval.
+	reader, err := startVideoReader(videoStream.Video, []string{
+		"-i", path,
+		"-map", fmt.Sprintf("0:%d", videoStream.Index),
+		"-filter:v", storyboardFilter(interval),
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	storyboard = &Storyboard{}
+	var sheet *image.RGBA
+	var img image.Image
+	for i := 0; time.Duration(i)*interval < mediaInfo.Duration; i++ {
+		start := time.Duration(i) * interval
+		end := start + interval
+		if end > mediaInfo.Duration {
+			end = mediaInfo.Duration
+		}
+		nextImg, err := reader.ReadFrame()
+		if err == io.EOF && img != nil {
+			// The final interval may be too short for the
+			// fps filter to produce a frame, in which case the
+			// last frame is still being shown.
+			nextImg = img
+		} else if err == io.EOF {
+			return nil, errors.Errorf("no frame at timestamp %v", start)
+		} else if err != nil {
+			return nil, err
+		}
+		img = nextImg
+		frame := &TimedFrame{Image: img, Time: start}
+		tileIndex := i % (rows * cols)
+		if tileIndex == 0 {
+			remaining := int((mediaInfo.Duration - start + interval - 1) / interval)
+			sheetRows := rows
+			if remaining < rows*cols {
+				sheetRows = (remaining + cols - 1) / cols
+			}
+			sheet = image.NewRGBA(image.Rect(0, 0, cols*tileWidth, sheetRows*tileHeight))
+			storyboard.Sheets = append(storyboard.Sheets, sheet)
+		}
+		x := (tileIndex % cols) * tileWidth
+		y := (tileIndex / cols) * tileHeight
+		tileRect := image.Rect(x, y, x+tileWidth, y+tileHeight)
+		drawTile(sheet, tileRect, frame, opts.Labels)
+		storyboard.Cues = append(storyboard.Cues, &StoryboardCue{
+			Start: start,
+			End:   end,
+			Sheet: len(storyboard.Sheets) - 1,
+			Tile:  tileRect,
+		})
+	}
+	return storyboard, nil
+}
+
+// storyboardFilter creates a filter which produces the
+// frame shown at the start of every interval.
+func storyboardFilter(interval time.Duration) string {
+	return fmt.Sprintf("setpts=PTS-STARTPTS,fps=fps=%d/%d:round=up",
+		time.Second/time.Microsecond, interval/time.Microsecond)
+}
+
+// Save writes the sprite sheets of the storyboard as PNG
+// files and creates a WebVTT file indexing them.
+//
+// The files are written to dir, and are named after the
+// given name, as in "name.vtt", "name_0.png", etc.
+func (s *Storyboard) Save(dir, name string) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "save storyboard")
+		}
+	}()
+
+	var sheetNames []string
+	for i, sheet := range s.Sheets {
+		sheetName := fmt.Sprintf("%s_%d.png", name, i)
+		sheetNames = append(sheetNames, sheetName)
+		if err := writePNG(filepath.Join(dir, sheetName), sheet); err != nil {
+			return err
+		}
+	}
+
+	lines := []string{"WEBVTT", ""}
+	for _, cue := range s.Cues {
+		r := cue.Tile
+		lines = append(
+			lines,
+			formatVTTTimestamp(cue.Start)+" --> "+formatVTTTimestamp(cue.End),
+			fmt.Sprintf("%s#xywh=%d,%d,%d,%d", sheetNames[cue.Sheet], r.Min.X, r.Min.Y, r.Dx(), r.Dy()),
+			"",
+		)
+	}
+	vttPath := filepath.Join(dir, name+".vtt")
+	return ioutil.WriteFile(vttPath, []byte(strings.Join(lines, "\n")), 0644)
+}
+
+func writePNG(path string, img image.Image) error {
+	w, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(w, img); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func formatVTTTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, (ms/60000)%60, (ms/1000)%60, ms%1000)
+}
+
+func tileSize(info *VideoInfo, width, height int) (int, int) {
+	if width == 0 && height == 0 {
+		width = 160
+	}
+	if width == 0 {
+		width = (height*info.Width + info.Height/2) / info.Height
+	} else if height == 0 {
+		height = (width*info.Height + info.Width/2) / info.Width
+	}
+	return width, height
+}
+
+func drawTile(dst *image.RGBA, rect image.Rectangle, frame *TimedFrame, label bool) {
+	resizeInto(dst, rect, frame.Image)
+	if label {
+		drawLabel(dst, rect, rect.Min.Add(image.Pt(2, 2)), formatLabelTimestamp(frame.Time))
+	}
+}
+
+// resizeInto draws a resized copy of src into the given
+// rectangle of dst, averaging the pixels of src that cover
+// each pixel of dst.
+func resizeInto(dst *image.RGBA, rect image.Rectangle, src image.Image) {
+	sb := src.Bounds()
+	for y := 0; y < rect.Dy(); y++ {
+		minY := sb.Min.Y + y*sb.Dy()/rect.Dy()
+		maxY := sb.Min.Y + (y+1)*sb.Dy()/rect.Dy()
+		if maxY == minY {
+			maxY++
+		}
+		for x := 0; x < rect.Dx(); x++ {
+			minX := sb.Min.X + x*sb.Dx()/rect.Dx()
+			maxX := sb.Min.X + (x+1)*sb.Dx()/rect.Dx()
+			if maxX == minX {
+				maxX++
+			}
+			var sum [4]uint32
+			var count uint32
+			for sy := minY; sy < maxY; sy++ {
+				for sx := minX; sx < maxX; sx++ {
+					r, g, b, a := src.At(sx, sy).RGBA()
+					sum[0] += r >> 8
+					sum[1] += g >> 8
+					sum[2] += b >> 8
+					sum[3] += a >> 8
+					count++
+				}
+			}
+			dst.SetRGBA(rect.Min.X+x, rect.Min.Y+y, color.RGBA{
+				R: uint8(sum[0] / count),
+				G: uint8(sum[1] / count),
+				B: uint8(sum[2] / count),
+				A: uint8(sum[3] / count),
+			})
+		}
+	}
+}
+
+func formatLabelTimestamp(d time.Duration) string {
+	s := int(d / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, (s/60)%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// labelFont is a 3x5 bitmap font for the characters in a
+// timestamp label. Each row is stored in the low 3 bits of
+// a byte, with the most significant bit on the left.
+var labelFont = map[rune][5]uint8{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 7, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 1, 1},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	':': {0, 2, 0, 2, 0},
+}
+
+// drawLabel draws white text on a black box, with each
+// font pixel drawn as a 2x2 square.
+//
+// The label is clipped to the tile rectangle, so that long
+// labels do not spill into neighboring tiles.
+func drawLabel(dst *image.RGBA, tile image.Rectangle, origin image.Point, text string) {
+	const scale = 2
+	clip := tile.Intersect(dst.Bounds())
+	const charWidth = 4 * scale
+	box := image.Rect(0, 0, len(text)*charWidth+scale, 7*scale).Add(origin)
+	draw.Draw(dst, box.Intersect(clip), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	for i, ch := range text {
+		glyph := labelFont[ch]
+		for row, bits := range glyph {
+			for col := 0; col < 3; col++ {
+				if bits&(4>>uint(col)) == 0 {
+					continue
+				}
+				px := box.Min.X + scale + i*charWidth + col*scale
+				py := box.Min.Y + scale + row*scale
+				rect := image.Rect(px, py, px+scale, py+scale)
+				draw.Draw(dst, rect.Intersect(clip), image.NewUniform(color.White),
+					image.Point{}, draw.Src)
+			}
+		}
+	}
+}
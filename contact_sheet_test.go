@@ -0,0 +1,103 @@
+package ffmpego
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContactSheet(t *testing.T) {
+	sheet, err := ContactSheet(filepath.Join("test_data", "test_video.mp4"), &ContactSheetOptions{
+		Rows:      2,
+		Columns:   3,
+		TileWidth: 32,
+		Spacing:   1,
+		Labels:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sheet.Bounds().Dx() != 3*33+1 || sheet.Bounds().Dy() != 2*17+1 {
+		t.Errorf("unexpected sheet size: %v", sheet.Bounds())
+	}
+}
+
+func TestStoryboard(t *testing.T) {
+	storyboard, err := CreateStoryboard(filepath.Join("test_data", "test_video.mp4"),
+		&StoryboardOptions{
+			Interval:  time.Second / 2,
+			Rows:      1,
+			Columns:   3,
+			TileWidth: 32,
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storyboard.Cues) != 4 || len(storyboard.Sheets) != 2 {
+		t.Fatalf("unexpected storyboard: %d cues, %d sheets", len(storyboard.Cues),
+			len(storyboard.Sheets))
+	}
+
+	dir, err := ioutil.TempDir("", "test-storyboard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := storyboard.Save(dir, "thumbs"); err != nil {
+		t.Fatal(err)
+	}
+	vtt, err := ioutil.ReadFile(filepath.Join(dir, "thumbs.vtt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(vtt), "00:00:01.500 --> 00:00:02.000\nthumbs_1.png#xywh=0,0,32,16") {
+		t.Errorf("unexpected VTT file:\n%s", vtt)
+	}
+	for _, name := range []string{"thumbs_0.png", "thumbs_1.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestTileSize(t *testing.T) {
+	info := &VideoInfo{Width: 1920, Height: 1080}
+	if w, h := tileSize(info, 0, 0); w != 160 || h != 90 {
+		t.Errorf("unexpected size: %dx%d", w, h)
+	}
+	if w, h := tileSize(info, 0, 45); w != 80 || h != 45 {
+		t.Errorf("unexpected size: %dx%d", w, h)
+	}
+	if w, h := tileSize(info, 10, 10); w != 10 || h != 10 {
+		t.Errorf("unexpected size: %dx%d", w, h)
+	}
+}
+
+func TestDrawLabelClipped(t *testing.T) {
+	dst := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	tile := image.Rect(0, 0, 20, 20)
+	drawLabel(dst, tile, image.Pt(2, 2), "0:00:00")
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			inTile := image.Pt(x, y).In(tile)
+			if drawn := dst.RGBAAt(x, y).A != 0; drawn && !inTile {
+				t.Fatalf("label drawn outside tile at (%d, %d)", x, y)
+			}
+		}
+	}
+	if dst.RGBAAt(2, 2).A == 0 {
+		t.Error("label was not drawn inside tile")
+	}
+}
+
+func TestStoryboardFilter(t *testing.T) {
+	actual := storyboardFilter(2500 * time.Millisecond)
+	expected := "setpts=PTS-STARTPTS,fps=fps=1000000/2500000:round=up"
+	if actual != expected {
+		t.Errorf("expected %s but got %s", expected, actual)
+	}
+}
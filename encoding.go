@@ -0,0 +1,143 @@
+package ffmpego
+
+import (
+	"strconv"
+)
+
+// VideoEncoding configures how video is encoded.
+//
+// Zero values are not passed to ffmpeg, so the encoder's
+// defaults are used for them.
+type VideoEncoding struct {
+	// Codec is the name of the ffmpeg encoder, such as
+	// "libx264", or "copy" to copy video without
+	// re-encoding it.
+	Codec string
+
+	// Preset is the encoder preset, such as "fast".
+	Preset string
+
+	// CRF is the constant rate factor, which controls the
+	// quality for encoders like libx264.
+	CRF int
+
+	// Bitrate is the target bitrate in bits per second.
+	Bitrate int
+
+	// PixelFormat is the output pixel format, such as
+	// "yuv420p".
+	PixelFormat string
+
+	// KeyframeInterval is the maximum number of frames
+	// between keyframes.
+	KeyframeInterval int
+
+	// ExtraFlags are additional output flags, such as
+	// []string{"-tune", "animation"}.
+	ExtraFlags []string
+}
+
+// DefaultVideoEncoding is the encoding used by writers when
+// no encoding is specified.
+//
+// The resulting videos are widely supported H.264 with a
+// high quality.
+func DefaultVideoEncoding() *VideoEncoding {
+	return &VideoEncoding{
+		Codec:       "libx264",
+		Preset:      "fast",
+		CRF:         18,
+		PixelFormat: "yuv420p",
+	}
+}
+
+func (v *VideoEncoding) flags() []string {
+	return v.specifierFlags(":v", "")
+}
+
+// streamFlags is like flags, but only applies to the
+// output video stream with the given index.
+//
+// ExtraFlags are passed unchanged, so they apply to every
+// stream unless they include their own specifiers.
+func (v *VideoEncoding) streamFlags(index int) []string {
+	spec := ":v:" + strconv.Itoa(index)
+	return v.specifierFlags(spec, spec)
+}
+
+// specifierFlags creates flags with a stream specifier for
+// the codec and bitrate, and another one for the rest of
+// the options, which need not be qualified when there is
+// only one video stream.
+func (v *VideoEncoding) specifierFlags(codecSpec, optionSpec string) []string {
+	var flags []string
+	if v.Codec != "" {
+		flags = append(flags, "-c"+codecSpec, v.Codec)
+	}
+	if v.Preset != "" {
+		flags = append(flags, "-preset"+optionSpec, v.Preset)
+	}
+	if v.CRF != 0 {
+		flags = append(flags, "-crf"+optionSpec, strconv.Itoa(v.CRF))
+	}
+	if v.Bitrate != 0 {
+		flags = append(flags, "-b"+codecSpec, strconv.Itoa(v.Bitrate))
+	}
+	if v.PixelFormat != "" {
+		flags = append(flags, "-pix_fmt"+optionSpec, v.PixelFormat)
+	}
+	if v.KeyframeInterval != 0 {
+		flags = append(flags, "-g"+optionSpec, strconv.Itoa(v.KeyframeInterval))
+	}
+	return append(flags, v.ExtraFlags...)
+}
+
+// needsEvenSize checks if the pixel format uses chroma
+// subsampling, so that frames must have even dimensions.
+func (v *VideoEncoding) needsEvenSize() bool {
+	switch v.PixelFormat {
+	case "yuv420p", "yuvj420p", "yuv422p", "yuvj422p", "nv12", "yuv420p10le":
+		return true
+	}
+	return false
+}
+
+// AudioEncoding configures how audio is encoded.
+//
+// Zero values are not passed to ffmpeg, so the encoder's
+// defaults are used for them.
+type AudioEncoding struct {
+	// Codec is the name of the ffmpeg encoder, such as
+	// "aac", or "copy" to copy audio without re-encoding
+	// it.
+	Codec string
+
+	// Bitrate is the target bitrate in bits per second.
+	Bitrate int
+
+	// Frequency is the sample rate in Hz.
+	Frequency int
+
+	// Channels is the number of audio channels.
+	Channels int
+
+	// ExtraFlags are additional output flags.
+	ExtraFlags []string
+}
+
+func (a *AudioEncoding) flags() []string {
+	var flags []string
+	if a.Codec != "" {
+		flags = append(flags, "-c:a", a.Codec)
+	}
+	if a.Bitrate != 0 {
+		flags = append(flags, "-b:a", strconv.Itoa(a.Bitrate))
+	}
+	if a.Frequency != 0 {
+		flags = append(flags, "-ar", strconv.Itoa(a.Frequency))
+	}
+	if a.Channels != 0 {
+		flags = append(flags, "-ac", strconv.Itoa(a.Channels))
+	}
+	return append(flags, a.ExtraFlags...)
+}
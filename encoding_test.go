@@ -0,0 +1,24 @@
+package ffmpego
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodingFlags(t *testing.T) {
+	video := &VideoEncoding{
+		Codec:      "libx265",
+		Preset:     "slow",
+		ExtraFlags: []string{"-tag:v", "hvc1"},
+	}
+	expected := []string{"-c:v", "libx265", "-preset", "slow", "-tag:v", "hvc1"}
+	if actual := video.flags(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+
+	audio := &AudioEncoding{Codec: "aac", Bitrate: 128000, Frequency: 48000, Channels: 2}
+	expected = []string{"-c:a", "aac", "-b:a", "128000", "-ar", "48000", "-ac", "2"}
+	if actual := audio.flags(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
@@ -1,11 +1,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"image"
 	"image/color"
-	"io"
 	"log"
 	"math"
 	"os"
@@ -55,15 +55,10 @@ func main() {
 
 	log.Println("Copying and blurring frames...")
 	filter := NewGaussianKernel(blurRadius, blurSigma)
-	for i := 0; true; i++ {
-		log.Printf("Blurring frame %d...", i+1)
-		frame, err := reader.ReadFrame()
-		if err == io.EOF {
-			break
-		}
-		frame = filter.Filter(frame)
-		essentials.Must(writer.WriteFrame(frame))
+	pipeline := &ffmpego.Pipeline{
+		Process: filter.Filter,
 	}
+	essentials.Must(pipeline.Run(context.Background(), reader, writer))
 }
 
 type GaussianKernel struct {
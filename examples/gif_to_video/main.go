@@ -2,9 +2,7 @@ package main
 
 import (
 	"fmt"
-	"image"
-	"image/color"
-	"image/gif"
+	"io"
 	"os"
 
 	"github.com/unixpickle/essentials"
@@ -19,88 +17,36 @@ func main() {
 	inputFile := os.Args[1]
 	outputFile := os.Args[2]
 
-	r, err := os.Open(inputFile)
-	essentials.Must(err)
-	defer r.Close()
-
-	gifImage, err := gif.DecodeAll(r)
+	// Frames are decoded along with their delays, so that
+	// animations with irregular timing are preserved.
+	reader, err := ffmpego.NewVideoReaderWithOptions(inputFile, &ffmpego.VideoReaderOptions{
+		Timestamps: true,
+	})
 	essentials.Must(err)
+	defer reader.Close()
 
-	delay := 0.0
-	for _, frameDelay := range gifImage.Delay {
-		delay += float64(frameDelay) / float64(100*len(gifImage.Delay))
+	info := reader.VideoInfo()
+	fps := info.FPS
+	if fps == 0 {
+		fps = 10
 	}
-	fps := 1 / delay
-
-	bounds := BoundsForGIF(gifImage)
-	writer, err := ffmpego.NewVideoWriter(outputFile, bounds.Dx(), bounds.Dy(), fps)
+	writer, err := ffmpego.NewVideoWriterWithOptions(outputFile, info.Width, info.Height, fps,
+		&ffmpego.VideoWriterOptions{VariableFrameRate: true})
 	essentials.Must(err)
 	defer func() {
 		essentials.Must(writer.Close())
 	}()
 
-	FramesFromGIF(gifImage, func(img image.Image) {
-		essentials.Must(writer.WriteFrame(img))
-	})
-}
-
-func BoundsForGIF(g *gif.GIF) image.Rectangle {
-	result := g.Image[0].Bounds()
-	for _, frame := range g.Image {
-		result = result.Union(frame.Bounds())
-	}
-	return result
-}
-
-func FramesFromGIF(g *gif.GIF, f func(image.Image)) {
-	out := image.NewRGBA(BoundsForGIF(g))
-	previous := image.NewRGBA(BoundsForGIF(g))
-	for i, frame := range g.Image {
-		disposal := g.Disposal[i]
-		switch disposal {
-		case 0:
-			clearImage(out)
-			clearImage(previous)
-			drawImageWithBackground(out, frame, out)
-			drawImageWithBackground(previous, frame, previous)
-		case gif.DisposalNone:
-			drawImageWithBackground(out, frame, out)
-		case gif.DisposalPrevious:
-			drawImageWithBackground(out, frame, previous)
-		case gif.DisposalBackground:
-			bgColor := g.Config.ColorModel.(color.Palette)[g.BackgroundIndex]
-			fillImage(out, bgColor)
-			drawImageWithBackground(out, frame, out)
+	for {
+		frame, err := reader.ReadTimedFrame()
+		if err == io.EOF {
+			break
 		}
-		f(out)
-	}
-}
-
-func drawImageWithBackground(dst *image.RGBA, src, bg image.Image) {
-	b := src.Bounds()
-	for y := b.Min.Y; y < b.Max.Y; y++ {
-		for x := b.Min.X; x < b.Max.X; x++ {
-			px := src.At(x, y)
-			_, _, _, a := px.RGBA()
-			if a == 0 {
-				px = bg.At(x, y)
-			}
-			dst.Set(x, y, px)
-		}
-	}
-}
-
-func clearImage(dst *image.RGBA) {
-	for i := range dst.Pix {
-		dst.Pix[i] = 0
-	}
-}
-
-func fillImage(dst *image.RGBA, c color.Color) {
-	b := dst.Bounds()
-	for y := b.Min.Y; y < b.Max.Y; y++ {
-		for x := b.Min.X; x < b.Max.X; x++ {
-			dst.Set(x, y, c)
+		essentials.Must(err)
+		if frame.Duration > 0 {
+			essentials.Must(writer.WriteFrameDuration(frame.Image, frame.Duration))
+		} else {
+			essentials.Must(writer.WriteFrame(frame.Image))
 		}
 	}
 }
@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"image"
 	"io"
 	"log"
 	"os"
@@ -19,27 +18,22 @@ func main() {
 	inputFile := os.Args[1]
 	outputFile := os.Args[2]
 
-	reader, err := ffmpego.NewVideoReader(inputFile)
+	reader, err := ffmpego.NewReverseVideoReader(inputFile)
 	essentials.Must(err)
 	defer reader.Close()
 	info := reader.VideoInfo()
 
-	log.Println("Reading video...")
-	var frames []image.Image
+	writer, err := ffmpego.NewVideoWriter(outputFile, info.Width, info.Height, info.FPS)
+	essentials.Must(err)
+	defer writer.Close()
+
+	log.Println("Reversing video...")
 	for {
 		frame, err := reader.ReadFrame()
 		if err == io.EOF {
 			break
 		}
 		essentials.Must(err)
-		frames = append(frames, frame)
-	}
-
-	log.Println("Encoding video...")
-	writer, err := ffmpego.NewVideoWriter(outputFile, info.Width, info.Height, info.FPS)
-	essentials.Must(err)
-	defer writer.Close()
-	for i := len(frames) - 1; i >= 0; i-- {
-		essentials.Must(writer.WriteFrame(frames[i]))
+		essentials.Must(writer.WriteFrame(frame))
 	}
 }
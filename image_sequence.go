@@ -0,0 +1,239 @@
+package ffmpego
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// NewImageSequenceReader creates a VideoReader which reads
+// a sequence of image files as frames of a video.
+//
+// The pattern may either be printf-style, as in
+// "frame_%05d.png", in which case the images are read in
+// numerical order starting at the lowest number found; or
+// a glob, as in "frames/*.png", in which case images are
+// read in lexical order.
+//
+// Glob patterns are not supported on Windows.
+func NewImageSequenceReader(pattern string, fps float64) (*VideoReader, error) {
+	if fps <= 0 {
+		panic("FPS must be positive")
+	}
+	vr, err := newImageSequenceReader(pattern, fps)
+	if err != nil {
+		err = errors.Wrap(err, "read image sequence")
+	}
+	return vr, err
+}
+
+func newImageSequenceReader(pattern string, fps float64) (*VideoReader, error) {
+	inputArgs, err := imageSequenceInput(pattern, fps)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pattern is not an actual file, so the usual
+	// existence check in getMediaInfo cannot be used.
+	lines, err := inputInfoLines(inputArgs...)
+	if err != nil {
+		return nil, err
+	}
+	mediaInfo, err := parseMediaInfo(lines)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := mediaInfo.selectStream(StreamTypeVideo, nil)
+	if err != nil {
+		return nil, err
+	}
+	if stream.Video.Width == 0 || stream.Video.Height == 0 {
+		return nil, errors.New("could not find dimensions in output")
+	}
+	info := *stream.Video
+	info.FPS = fps
+
+	args := append(inputArgs, "-map", fmt.Sprintf("0:%d", stream.Index))
+	return startVideoReader(&info, args, false)
+}
+
+// imageSequenceInput gets the ffmpeg input flags for an
+// image sequence.
+func imageSequenceInput(pattern string, fps float64) ([]string, error) {
+	args := []string{"-f", "image2", "-framerate", fmt.Sprintf("%f", fps)}
+	if !isPrintfPattern(pattern) {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, errors.New("no images match pattern: " + pattern)
+		}
+		return append(args, "-pattern_type", "glob", "-i", pattern), nil
+	}
+	start, err := printfPatternStart(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return append(args, "-start_number", strconv.Itoa(start), "-i", pattern), nil
+}
+
+// printfPatternExp matches the numbers which ffmpeg can
+// substitute into a file name: "%d" or a zero-padded
+// "%0Nd". Other widths, such as the space-padded "%5d",
+// are not supported.
+var printfPatternExp = regexp.MustCompilePOSIX("%(0[0-9]+|)d|%%")
+
+func isPrintfPattern(pattern string) bool {
+	for _, match := range printfPatternExp.FindAllString(pattern, -1) {
+		if match != "%%" {
+			return true
+		}
+	}
+	return false
+}
+
+// printfPatternStart finds the lowest number of an
+// existing file matching a printf-style pattern.
+func printfPatternStart(pattern string) (int, error) {
+	glob, exp, err := printfPatternMatchers(pattern)
+	if err != nil {
+		return 0, err
+	}
+	matches, err := filepath.Glob(glob)
+	if err != nil {
+		return 0, err
+	}
+	start := -1
+	for _, match := range matches {
+		submatch := exp.FindStringSubmatch(match)
+		if submatch == nil {
+			continue
+		}
+		n, err := strconv.Atoi(submatch[1])
+		if err != nil {
+			continue
+		}
+		if start == -1 || n < start {
+			start = n
+		}
+	}
+	if start == -1 {
+		return 0, errors.New("no images match pattern: " + pattern)
+	}
+	return start, nil
+}
+
+// printfPatternMatchers converts a printf-style pattern to
+// a glob which matches a superset of the files, and a
+// regular expression which captures the number in each
+// matching file.
+func printfPatternMatchers(pattern string) (string, *regexp.Regexp, error) {
+	globEscaper := strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`, `\`, `\\`)
+	var glob, exp strings.Builder
+	exp.WriteString("^")
+	last := 0
+	numbers := 0
+	for _, loc := range printfPatternExp.FindAllStringSubmatchIndex(pattern, -1) {
+		literal := pattern[last:loc[0]]
+		glob.WriteString(globEscaper.Replace(literal))
+		exp.WriteString(regexp.QuoteMeta(literal))
+		last = loc[1]
+		if pattern[loc[0]:loc[1]] == "%%" {
+			glob.WriteString("%")
+			exp.WriteString("%")
+			continue
+		}
+		numbers++
+		if numbers > 1 {
+			return "", nil, errors.New("pattern contains multiple numbers: " + pattern)
+		}
+		glob.WriteString("*")
+		width := strings.TrimPrefix(pattern[loc[2]:loc[3]], "0")
+		if width == "" {
+			exp.WriteString("([0-9]+)")
+		} else {
+			exp.WriteString("([0-9]{" + width + ",})")
+		}
+	}
+	glob.WriteString(globEscaper.Replace(pattern[last:]))
+	exp.WriteString(regexp.QuoteMeta(pattern[last:]))
+	exp.WriteString("$")
+	compiled, err := regexp.Compile(exp.String())
+	if err != nil {
+		return "", nil, err
+	}
+	return glob.String(), compiled, nil
+}
+
+// ImageSequenceWriterOptions configures how images are
+// encoded by an image sequence writer.
+type ImageSequenceWriterOptions struct {
+	// Quality is the quality of JPEG images, from 1 (worst)
+	// to 100 (best). If 0, 90 is used.
+	//
+	// This is ignored for lossless formats.
+	Quality int
+
+	// StartNumber is the number of the first image.
+	StartNumber int
+}
+
+// NewImageSequenceWriter creates a VideoWriter which saves
+// each frame as a separate image file.
+//
+// The pattern should be printf-style, as in
+// "frame_%05d.png". The image format is determined by its
+// extension, which may be .png, .jpg, .jpeg, .tif, .tiff,
+// or .exr.
+func NewImageSequenceWriter(pattern string, width, height int,
+	opts *ImageSequenceWriterOptions) (*VideoWriter, error) {
+	encoding, err := imageSequenceEncoding(pattern, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "write image sequence")
+	}
+	vw, err := newVideoWriter(pattern, width, height, 1, &VideoWriterOptions{Encoding: encoding})
+	if err != nil {
+		err = errors.Wrap(err, "write image sequence")
+	}
+	return vw, err
+}
+
+func imageSequenceEncoding(pattern string, opts *ImageSequenceWriterOptions) (*VideoEncoding, error) {
+	var encoding *VideoEncoding
+	switch strings.ToLower(filepath.Ext(pattern)) {
+	case ".png":
+		encoding = &VideoEncoding{Codec: "png", PixelFormat: "rgb24"}
+	case ".jpg", ".jpeg":
+		quality := opts.Quality
+		if quality == 0 {
+			quality = 90
+		}
+		if quality < 1 || quality > 100 {
+			return nil, errors.New("JPEG quality must be in the range [1, 100]")
+		}
+		// The mjpeg encoder uses a scale from 2 (best) to 31
+		// (worst).
+		q := 2 + (100-quality)*29/99
+		encoding = &VideoEncoding{
+			Codec:       "mjpeg",
+			PixelFormat: "yuvj444p",
+			ExtraFlags:  []string{"-q:v", strconv.Itoa(q)},
+		}
+	case ".tif", ".tiff":
+		encoding = &VideoEncoding{Codec: "tiff", PixelFormat: "rgb24"}
+	case ".exr":
+		encoding = &VideoEncoding{Codec: "exr", PixelFormat: "gbrpf32le"}
+	default:
+		return nil, errors.New("unsupported image format: " + filepath.Ext(pattern))
+	}
+	encoding.ExtraFlags = append(
+		encoding.ExtraFlags,
+		"-f", "image2", "-start_number", strconv.Itoa(opts.StartNumber),
+	)
+	return encoding, nil
+}
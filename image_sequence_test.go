@@ -0,0 +1,134 @@
+package ffmpego
+
+import (
+	"image"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImageSequence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-image-sequence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	reader, err := NewVideoReader(filepath.Join("test_data", "test_video.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	writer, err := NewImageSequenceWriter(filepath.Join(dir, "frame_%03d.png"), 64, 32,
+		&ImageSequenceWriterOptions{StartNumber: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var frames []image.Image
+	for {
+		frame, err := reader.ReadFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			writer.Close()
+			t.Fatal(err)
+		}
+		frames = append(frames, frame)
+		if err := writer.WriteFrame(frame); err != nil {
+			writer.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "frame_010.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pattern := range []string{"frame_%03d.png", "frame_*.png"} {
+		seqReader, err := NewImageSequenceReader(filepath.Join(dir, pattern), 12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, expected := range frames {
+			actual, err := seqReader.ReadFrame()
+			if err != nil {
+				seqReader.Close()
+				t.Fatal(err)
+			}
+			if !imagesEqual(actual, expected) {
+				t.Errorf("%s: frame %d is incorrect", pattern, i)
+			}
+		}
+		if _, err := seqReader.ReadFrame(); err != io.EOF {
+			t.Errorf("%s: expected EOF but got %v", pattern, err)
+		}
+		seqReader.Close()
+	}
+}
+
+func TestPrintfPatternStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-image-sequence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"img_0007.png", "img_0012.png", "img_12345.png", "img_x.png",
+		"other_0001.png"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start, err := printfPatternStart(filepath.Join(dir, "img_%04d.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 7 {
+		t.Errorf("expected start 7 but got %d", start)
+	}
+	if _, err := printfPatternStart(filepath.Join(dir, "missing_%d.png")); err == nil {
+		t.Error("expected error for missing images")
+	}
+}
+
+func TestPrintfPatternMatchers(t *testing.T) {
+	if !isPrintfPattern("a_%05d.png") || isPrintfPattern("100%%.png") || isPrintfPattern("*.png") {
+		t.Error("incorrect printf pattern detection")
+	}
+	if !isPrintfPattern("a_%d.png") || isPrintfPattern("a_%5d.png") || isPrintfPattern("a_%-3d.png") {
+		t.Error("only plain and zero-padded numbers should be printf patterns")
+	}
+	glob, exp, err := printfPatternMatchers("50%%_[x]_%03d.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if glob != `50%_\[x]_*.jpg` {
+		t.Errorf("unexpected glob: %s", glob)
+	}
+	if m := exp.FindStringSubmatch("50%_[x]_0042.jpg"); m == nil || m[1] != "0042" {
+		t.Errorf("unexpected match: %v", m)
+	}
+	if exp.MatchString("50%_[x]_42.jpg") {
+		t.Error("number should be at least 3 digits")
+	}
+}
+
+func TestImageSequenceEncoding(t *testing.T) {
+	encoding, err := imageSequenceEncoding("out_%d.JPG", &ImageSequenceWriterOptions{Quality: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"-c:v", "mjpeg", "-pix_fmt", "yuvj444p", "-q:v", "2",
+		"-f", "image2", "-start_number", "0"}
+	if actual := encoding.flags(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+	if _, err := imageSequenceEncoding("out_%d.gif", &ImageSequenceWriterOptions{}); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,193 @@
+package ffmpego
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// An Interval is a range of time in a media file.
+type Interval struct {
+	Start    time.Duration
+	End      time.Duration
+	Duration time.Duration
+}
+
+// BlackDetectOptions configures DetectBlack.
+//
+// Zero values use ffmpeg's defaults.
+type BlackDetectOptions struct {
+	// MinDuration is the minimum length of a black interval.
+	MinDuration time.Duration
+
+	// PictureThreshold is the fraction of pixels which must
+	// be black for a frame to be considered black.
+	PictureThreshold float64
+
+	// PixelThreshold is the luminance, from 0 to 1, below
+	// which a pixel is considered black.
+	PixelThreshold float64
+}
+
+// DetectBlack finds intervals of a video where the frames
+// are black, using ffmpeg's blackdetect filter.
+func DetectBlack(path string, opts *BlackDetectOptions) (intervals []*Interval, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "detect black frames")
+		}
+	}()
+	var params []string
+	if opts.MinDuration != 0 {
+		params = append(params, "d="+formatSeconds(opts.MinDuration))
+	}
+	if opts.PictureThreshold != 0 {
+		params = append(params, fmt.Sprintf("pic_th=%f", opts.PictureThreshold))
+	}
+	if opts.PixelThreshold != 0 {
+		params = append(params, fmt.Sprintf("pix_th=%f", opts.PixelThreshold))
+	}
+	return runIntervalDetection(path, StreamTypeVideo, filterWithParams("blackdetect", params),
+		"black_start", "black_end")
+}
+
+// FreezeDetectOptions configures DetectFreezes.
+//
+// Zero values use ffmpeg's defaults.
+type FreezeDetectOptions struct {
+	// MinDuration is the minimum length of a frozen
+	// interval.
+	MinDuration time.Duration
+
+	// NoiseTolerance is the fraction of difference between
+	// frames which is still considered frozen.
+	NoiseTolerance float64
+}
+
+// DetectFreezes finds intervals of a video where the
+// frames do not change, using ffmpeg's freezedetect
+// filter.
+func DetectFreezes(path string, opts *FreezeDetectOptions) (intervals []*Interval, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "detect frozen frames")
+		}
+	}()
+	var params []string
+	if opts.MinDuration != 0 {
+		params = append(params, "d="+formatSeconds(opts.MinDuration))
+	}
+	if opts.NoiseTolerance != 0 {
+		params = append(params, fmt.Sprintf("n=%f", opts.NoiseTolerance))
+	}
+	return runIntervalDetection(path, StreamTypeVideo, filterWithParams("freezedetect", params),
+		"lavfi.freezedetect.freeze_start", "lavfi.freezedetect.freeze_end")
+}
+
+// SilenceDetectOptions configures DetectSilence.
+//
+// Zero values use ffmpeg's defaults.
+type SilenceDetectOptions struct {
+	// MinDuration is the minimum length of a silent
+	// interval.
+	MinDuration time.Duration
+
+	// NoiseThreshold is the volume in decibels, such as -50,
+	// below which audio is considered silent.
+	NoiseThreshold float64
+}
+
+// DetectSilence finds intervals of a file where the audio
+// is silent, using ffmpeg's silencedetect filter.
+func DetectSilence(path string, opts *SilenceDetectOptions) (intervals []*Interval, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "detect silence")
+		}
+	}()
+	var params []string
+	if opts.MinDuration != 0 {
+		params = append(params, "d="+formatSeconds(opts.MinDuration))
+	}
+	if opts.NoiseThreshold != 0 {
+		params = append(params, fmt.Sprintf("n=%fdB", opts.NoiseThreshold))
+	}
+	return runIntervalDetection(path, StreamTypeAudio, filterWithParams("silencedetect", params),
+		"silence_start", "silence_end")
+}
+
+func filterWithParams(name string, params []string) string {
+	if len(params) == 0 {
+		return name
+	}
+	return name + "=" + strings.Join(params, ":")
+}
+
+func runIntervalDetection(path string, streamType StreamType, filter, startKey,
+	endKey string) ([]*Interval, error) {
+	mediaInfo, err := getMediaInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := mediaInfo.selectStream(streamType, nil)
+	if err != nil {
+		return nil, err
+	}
+	filterFlag := "-filter:v"
+	if streamType == StreamTypeAudio {
+		filterFlag = "-filter:a"
+	}
+	lines, err := runFFmpeg(
+		context.Background(),
+		"-i", path,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		filterFlag, filter,
+		"-f", "null", "-",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return parseIntervals(lines, startKey, endKey, mediaInfo.Duration)
+}
+
+// parseIntervals finds logged start and end times of
+// intervals.
+//
+// If an interval is still in progress at the end of the
+// file, it is ended at the given duration.
+func parseIntervals(lines []string, startKey, endKey string, duration time.Duration) ([]*Interval, error) {
+	startExp := regexp.MustCompilePOSIX(regexp.QuoteMeta(startKey) + ": *([-0-9\\.]+)")
+	endExp := regexp.MustCompilePOSIX(regexp.QuoteMeta(endKey) + ": *([-0-9\\.]+)")
+
+	var result []*Interval
+	var current *Interval
+	for _, line := range lines {
+		if match := startExp.FindStringSubmatch(line); match != nil {
+			start, err := parseSeconds(match[1])
+			if err != nil {
+				return nil, err
+			}
+			current = &Interval{Start: start}
+		}
+		if match := endExp.FindStringSubmatch(line); match != nil && current != nil {
+			end, err := parseSeconds(match[1])
+			if err != nil {
+				return nil, err
+			}
+			current.End = end
+			current.Duration = end - current.Start
+			result = append(result, current)
+			current = nil
+		}
+	}
+	if current != nil && duration > current.Start {
+		current.End = duration
+		current.Duration = duration - current.Start
+		result = append(result, current)
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package ffmpego
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectSilence(t *testing.T) {
+	intervals, err := DetectSilence(filepath.Join("test_data", "test_audio.wav"),
+		&SilenceDetectOptions{MinDuration: time.Second / 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, interval := range intervals {
+		if interval.End < interval.Start || interval.Duration != interval.End-interval.Start {
+			t.Errorf("invalid interval: %v", interval)
+		}
+	}
+}
+
+func TestDetectBlack(t *testing.T) {
+	intervals, err := DetectBlack(filepath.Join("test_data", "test_video.mp4"),
+		&BlackDetectOptions{MinDuration: time.Second / 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, interval := range intervals {
+		if interval.End < interval.Start {
+			t.Errorf("invalid interval: %v", interval)
+		}
+	}
+}
+
+func TestParseIntervals(t *testing.T) {
+	log := `[blackdetect @ 0x55b1] black_start:0 black_end:2.04 black_duration:2.04
+[freezedetect @ 0x55b2] lavfi.freezedetect.freeze_start: 1.001
+[freezedetect @ 0x55b2] lavfi.freezedetect.freeze_duration: 2.5
+[freezedetect @ 0x55b2] lavfi.freezedetect.freeze_end: 3.501
+[silencedetect @ 0x55b3] silence_start: 1.5
+[silencedetect @ 0x55b3] silence_end: 3.5 | silence_duration: 2
+[silencedetect @ 0x55b3] silence_start: 8`
+	lines := strings.Split(log, "\n")
+
+	tests := []struct {
+		StartKey string
+		EndKey   string
+		Expected []Interval
+	}{
+		{
+			StartKey: "black_start",
+			EndKey:   "black_end",
+			Expected: []Interval{{0, 2040 * time.Millisecond, 2040 * time.Millisecond}},
+		},
+		{
+			StartKey: "lavfi.freezedetect.freeze_start",
+			EndKey:   "lavfi.freezedetect.freeze_end",
+			Expected: []Interval{{1001 * time.Millisecond, 3501 * time.Millisecond, 2500 * time.Millisecond}},
+		},
+		{
+			StartKey: "silence_start",
+			EndKey:   "silence_end",
+			Expected: []Interval{
+				{1500 * time.Millisecond, 3500 * time.Millisecond, 2 * time.Second},
+				{8 * time.Second, 10 * time.Second, 2 * time.Second},
+			},
+		},
+	}
+	for _, test := range tests {
+		intervals, err := parseIntervals(lines, test.StartKey, test.EndKey, 10*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(intervals) != len(test.Expected) {
+			t.Errorf("%s: expected %d intervals but got %d", test.StartKey, len(test.Expected),
+				len(intervals))
+			continue
+		}
+		for i, interval := range intervals {
+			if *interval != test.Expected[i] {
+				t.Errorf("%s: expected %v but got %v", test.StartKey, test.Expected[i], *interval)
+			}
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package ffmpego
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// GetKeyframes finds the timestamps of the keyframes in the
+// first video stream of a file.
+//
+// Timestamps are relative to the first frame of the video.
+//
+// This only reads packet headers from the file, so it is
+// much faster than decoding the video.
+func GetKeyframes(path string) ([]time.Duration, error) {
+	return GetKeyframesStream(path, nil)
+}
+
+// GetKeyframesStream is like GetKeyframes, but allows the
+// caller to select a video stream.
+func GetKeyframesStream(path string, selector StreamSelector) (keyframes []time.Duration, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "get keyframes")
+		}
+	}()
+	stream, _, err := getVideoStream(path, selector)
+	if err != nil {
+		return nil, err
+	}
+	index, err := probeFrameIndex(path, stream.Index)
+	if err != nil {
+		return nil, err
+	}
+	start := index.Seconds(0)
+	for i, key := range index.Key {
+		if key {
+			offset := index.Seconds(i) - start
+			keyframes = append(keyframes, time.Duration(offset*float64(time.Second)))
+		}
+	}
+	return keyframes, nil
+}
+
+// A frameIndex records the exact timestamp of every frame
+// in a video stream, in presentation order.
+type frameIndex struct {
+	// TimeBase is the numerator and denominator of the
+	// unit of the timestamps.
+	TimeBase [2]int64
+
+	// PTS stores absolute presentation timestamps,
+	// as they appear in the file.
+	PTS []int64
+
+	// Key indicates which frames are keyframes.
+	Key []bool
+}
+
+// probeFrameIndex reads the packet headers for a stream
+// without decoding it.
+func probeFrameIndex(path string, streamIndex int) (*frameIndex, error) {
+	lines, err := runFFmpegStdout(
+		context.Background(),
+		"-copyts", "-i", path,
+		"-map", fmt.Sprintf("0:%d", streamIndex),
+		"-c", "copy", "-f", "framecrc", "-",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return parseFrameIndex(lines)
+}
+
+func parseFrameIndex(lines []string) (*frameIndex, error) {
+	type frame struct {
+		PTS int64
+		Key bool
+	}
+	var frames []frame
+	result := &frameIndex{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#tb 0:") {
+			parts := strings.Split(strings.TrimSpace(line[len("#tb 0:"):]), "/")
+			if len(parts) != 2 {
+				return nil, errors.New("parse time base: " + line)
+			}
+			for i, part := range parts {
+				n, err := strconv.ParseInt(part, 10, 64)
+				if err != nil {
+					return nil, errors.Wrap(err, "parse time base")
+				}
+				result.TimeBase[i] = n
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) < 6 {
+			continue
+		}
+		pts, err := strconv.ParseInt(strings.TrimSpace(fields[2]), 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse pts")
+		}
+		if pts == math.MinInt64 {
+			// The packet has no timestamp.
+			continue
+		}
+		key := true
+		for _, field := range fields[6:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "F=0x") {
+				flags, err := strconv.ParseInt(field[4:], 16, 64)
+				if err != nil {
+					return nil, errors.Wrap(err, "parse flags")
+				}
+				key = flags&1 != 0
+			}
+		}
+		frames = append(frames, frame{PTS: pts, Key: key})
+	}
+	if result.TimeBase[0] == 0 || result.TimeBase[1] == 0 {
+		return nil, errors.New("could not find time base in output")
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("no frames found")
+	}
+	sort.SliceStable(frames, func(i, j int) bool {
+		return frames[i].PTS < frames[j].PTS
+	})
+	for _, f := range frames {
+		result.PTS = append(result.PTS, f.PTS)
+		result.Key = append(result.Key, f.Key)
+	}
+	return result, nil
+}
+
+// Seconds gets the timestamp of the i-th frame in seconds.
+func (f *frameIndex) Seconds(i int) float64 {
+	return float64(f.PTS[i]) * float64(f.TimeBase[0]) / float64(f.TimeBase[1])
+}
+
+// A videoChunk is a range of frames, starting at a
+// keyframe, which can be decoded independently.
+type videoChunk struct {
+	FirstFrame int
+	NumFrames  int
+
+	// Seek is the absolute timestamp to seek to, which lies
+	// between the first frame and the one after it.
+	// It is negative for chunks at the start of the video.
+	Seek float64
+
+	// Start and End are absolute timestamps between the
+	// boundary frames of the chunk and their neighbors.
+	// They are infinite at the ends of the video.
+	Start float64
+	End   float64
+}
+
+// Chunks splits the video into keyframe-aligned chunks of
+// at least minFrames frames, except for the final chunk.
+func (f *frameIndex) Chunks(minFrames int) []*videoChunk {
+	var starts []int
+	for i, key := range f.Key {
+		if i == 0 || (key && i-starts[len(starts)-1] >= minFrames) {
+			starts = append(starts, i)
+		}
+	}
+	var result []*videoChunk
+	for i, start := range starts {
+		end := len(f.PTS)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		chunk := &videoChunk{
+			FirstFrame: start,
+			NumFrames:  end - start,
+			Seek:       -1,
+			Start:      math.Inf(-1),
+			End:        math.Inf(1),
+		}
+		if start > 0 {
+			chunk.Start = f.midpoint(start-1, start)
+			if start+1 < len(f.PTS) {
+				chunk.Seek = f.midpoint(start, start+1)
+			} else {
+				chunk.Seek = f.Seconds(start)
+			}
+		}
+		if end < len(f.PTS) {
+			chunk.End = f.midpoint(end-1, end)
+		}
+		result = append(result, chunk)
+	}
+	return result
+}
+
+// subChunk creates a chunk which decodes the frames in
+// [start, end) by seeking to the given keyframe and
+// discarding the frames before start.
+func (f *frameIndex) subChunk(keyframe, start, end int) *videoChunk {
+	chunk := &videoChunk{
+		FirstFrame: start,
+		NumFrames:  end - start,
+		Seek:       -1,
+		Start:      math.Inf(-1),
+		End:        math.Inf(1),
+	}
+	if keyframe > 0 {
+		chunk.Seek = f.Seconds(keyframe)
+		if keyframe+1 < len(f.PTS) {
+			chunk.Seek = f.midpoint(keyframe, keyframe+1)
+		}
+	}
+	if start > 0 {
+		chunk.Start = f.midpoint(start-1, start)
+	}
+	if end < len(f.PTS) {
+		chunk.End = f.midpoint(end-1, end)
+	}
+	return chunk
+}
+
+func (f *frameIndex) midpoint(i, j int) float64 {
+	return (f.Seconds(i) + f.Seconds(j)) / 2
+}
+
+// Args gets the ffmpeg arguments for decoding the frames in
+// a chunk of a video stream.
+func (c *videoChunk) Args(path string, streamIndex int) []string {
+	// Timestamps are kept absolute, so that they match the
+	// ones found by probeFrameIndex.
+	args := []string{"-copyts"}
+	if c.Seek >= 0 {
+		args = append(
+			args,
+			"-seek_timestamp", "1", "-noaccurate_seek",
+			"-ss", fmt.Sprintf("%f", c.Seek),
+		)
+	}
+	args = append(
+		args,
+		"-i", path,
+		"-map", fmt.Sprintf("0:%d", streamIndex),
+		"-vsync", "passthrough",
+	)
+	var conditions []string
+	if !math.IsInf(c.Start, 0) {
+		conditions = append(conditions, fmt.Sprintf("gte(t,%f)", c.Start))
+	}
+	if !math.IsInf(c.End, 0) {
+		conditions = append(conditions, fmt.Sprintf("lt(t,%f)", c.End))
+	}
+	if len(conditions) > 0 {
+		args = append(args, "-filter:v", "select='"+strings.Join(conditions, "*")+"'")
+	}
+	return append(args, "-frames:v", strconv.Itoa(c.NumFrames))
+}
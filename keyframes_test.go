@@ -0,0 +1,84 @@
+package ffmpego
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testFrameCRCOutput = `#format: frame checksums
+#version: 2
+#hash: CRC-32
+#tb 0: 1/12288
+#media_type 0: video
+#codec_id 0: h264
+#dimensions 0: 64x32
+#sar 0: 1/1
+#stream#, dts,        pts, duration,     size, checksum
+0,      -2048,          0,     1024,      912, 0x4ef8c7b4
+0,      -1024,       3072,     1024,       44, 0x0d4a1176, F=0x0
+0,          0,       1024,     1024,       32, 0x07a00c15, F=0x0
+0,       1024,       2048,     1024,       32, 0x07c00c29, F=0x0
+0,       2048,       4096,     1024,      512, 0x0bd1120a
+0,       3072,       6144,     1024,       44, 0x0d4a1176, F=0x0
+0,       4096,       5120,     1024,       32, 0x07a00c15, F=0x0
+0,       5120,       7168,     1024,      512, 0x0bd1120a, S=1,        8
+`
+
+func TestGetKeyframes(t *testing.T) {
+	keyframes, err := GetKeyframes(filepath.Join("test_data", "test_video.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keyframes) == 0 || keyframes[0] != 0 {
+		t.Errorf("unexpected keyframes: %v", keyframes)
+	}
+}
+
+func TestParseFrameIndex(t *testing.T) {
+	index, err := parseFrameIndex(strings.Split(testFrameCRCOutput, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.TimeBase != [2]int64{1, 12288} {
+		t.Errorf("bad time base: %v", index.TimeBase)
+	}
+	expectedPTS := []int64{0, 1024, 2048, 3072, 4096, 5120, 6144, 7168}
+	expectedKey := []bool{true, false, false, false, true, false, false, true}
+	if len(index.PTS) != len(expectedPTS) {
+		t.Fatalf("expected %d frames but got %d", len(expectedPTS), len(index.PTS))
+	}
+	for i, pts := range expectedPTS {
+		if index.PTS[i] != pts || index.Key[i] != expectedKey[i] {
+			t.Errorf("frame %d: expected (%d, %v) but got (%d, %v)", i, pts, expectedKey[i],
+				index.PTS[i], index.Key[i])
+		}
+	}
+
+	chunks := index.Chunks(2)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks but got %d", len(chunks))
+	}
+	firstFrames := []int{0, 4, 7}
+	numFrames := []int{4, 3, 1}
+	for i, chunk := range chunks {
+		if chunk.FirstFrame != firstFrames[i] || chunk.NumFrames != numFrames[i] {
+			t.Errorf("chunk %d: bad range %d+%d", i, chunk.FirstFrame, chunk.NumFrames)
+		}
+	}
+	if chunks[0].Seek >= 0 || !math.IsInf(chunks[0].Start, -1) {
+		t.Error("first chunk should start at the beginning")
+	}
+	frameTime := 1024.0 / 12288.0
+	if math.Abs(chunks[1].Seek-4.5*frameTime) > 1e-8 {
+		t.Errorf("bad seek time: %f", chunks[1].Seek)
+	}
+	if math.Abs(chunks[1].Start-3.5*frameTime) > 1e-8 ||
+		math.Abs(chunks[1].End-6.5*frameTime) > 1e-8 {
+		t.Errorf("bad chunk bounds: %f-%f", chunks[1].Start, chunks[1].End)
+	}
+	if !math.IsInf(chunks[2].End, 1) {
+		t.Error("last chunk should end at the end of the video")
+	}
+}
@@ -0,0 +1,175 @@
+package ffmpego
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultLadder creates a typical set of H.264 renditions
+// from 240p to 1080p.
+func DefaultLadder() []*Rendition {
+	rungs := []struct {
+		Height  int
+		Bitrate int
+	}{
+		{240, 400000},
+		{360, 800000},
+		{480, 1400000},
+		{720, 2800000},
+		{1080, 5000000},
+	}
+	var result []*Rendition
+	for _, rung := range rungs {
+		encoding := DefaultVideoEncoding()
+		encoding.CRF = 0
+		encoding.Bitrate = rung.Bitrate
+		result = append(result, &Rendition{
+			Name:   fmt.Sprintf("%dp", rung.Height),
+			Height: rung.Height,
+			Video:  encoding,
+		})
+	}
+	return result
+}
+
+// LadderOptions configures how EncodeLadder encodes a set
+// of renditions.
+type LadderOptions struct {
+	// Renditions are the encodings of the video.
+	// If empty, DefaultLadder() is used.
+	Renditions []*Rendition
+
+	// KeyframeInterval is the time between keyframes, which
+	// are placed at the same timestamps in every rendition.
+	// If 0, 2 seconds is used.
+	KeyframeInterval time.Duration
+
+	// Extension is the file extension of the outputs.
+	// If empty, ".mp4" is used.
+	Extension string
+
+	// Audio configures the audio encoder for every output.
+	// If nil, 128 kb/s AAC is used.
+	Audio *AudioEncoding
+
+	// NoAudio drops the audio from the outputs.
+	NoAudio bool
+
+	// NoUpscale skips renditions which are larger than the
+	// input video.
+	NoUpscale bool
+
+	// Progress, if non-nil, is called periodically while
+	// the renditions are being encoded.
+	Progress func(p *TranscodeProgress)
+}
+
+// EncodeLadder encodes a video as several renditions for
+// adaptive bitrate streaming, writing each one to a file in
+// dir which is named after the rendition.
+//
+// All of the renditions are encoded by one ffmpeg process,
+// which decodes the input only once. Keyframes are aligned
+// across renditions so that players can switch between
+// them at any keyframe.
+//
+// The paths of the outputs are returned in the order of
+// the renditions which were encoded.
+func EncodeLadder(ctx context.Context, input, dir string, opts *LadderOptions) (paths []string,
+	err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "encode ladder")
+		}
+	}()
+
+	renditions := opts.Renditions
+	if len(renditions) == 0 {
+		renditions = DefaultLadder()
+	}
+	if err := checkRenditionNames(renditions); err != nil {
+		return nil, err
+	}
+	interval := opts.KeyframeInterval
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+	extension := opts.Extension
+	if extension == "" {
+		extension = ".mp4"
+	}
+	audioEncoding := opts.Audio
+	if audioEncoding == nil {
+		audioEncoding = &AudioEncoding{Codec: "aac", Bitrate: 128000}
+	}
+
+	info, err := getMediaInfo(input)
+	if err != nil {
+		return nil, err
+	}
+	video, err := info.selectStream(StreamTypeVideo, nil)
+	if err != nil {
+		return nil, err
+	}
+	var audio *StreamInfo
+	if !opts.NoAudio {
+		audio, _ = selectOptionalStream(info, StreamTypeAudio, nil)
+	}
+	if opts.NoUpscale {
+		renditions = filterUpscaling(renditions, video.Video)
+		if len(renditions) == 0 {
+			return nil, errors.New("every rendition is larger than the input")
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	args := []string{"-y", "-i", input, "-filter_complex", renditionFilter(video.Index, renditions)}
+	for i, r := range renditions {
+		path := filepath.Join(dir, r.Name+extension)
+		paths = append(paths, path)
+		args = append(args, "-map", fmt.Sprintf("[r%d]", i))
+		args = append(args, r.encoding().flags()...)
+		args = append(args, alignedKeyframeArgs(interval)...)
+		// Scene detection would add keyframes which differ
+		// between renditions.
+		args = append(args, "-sc_threshold", "0")
+		if audio != nil {
+			args = append(args, "-map", fmt.Sprintf("0:%d", audio.Index))
+			args = append(args, audioEncoding.flags()...)
+		}
+		args = append(args, path)
+	}
+
+	if opts.Progress == nil {
+		_, err = runFFmpeg(ctx, args...)
+	} else {
+		_, err = runFFmpegProgress(ctx, func(values map[string]string) {
+			opts.Progress(parseTranscodeProgress(values, info.Duration))
+		}, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// filterUpscaling removes renditions which are larger than
+// the input in either dimension.
+func filterUpscaling(renditions []*Rendition, info *VideoInfo) []*Rendition {
+	var result []*Rendition
+	for _, r := range renditions {
+		if r.Width > info.Width || r.Height > info.Height {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+package ffmpego
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncodeLadder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-ladder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	renditions := []*Rendition{
+		{Name: "small", Height: 16, Video: &VideoEncoding{Codec: "libx264", Bitrate: 50000}},
+		{Name: "full", Video: &VideoEncoding{Codec: "libx264", Bitrate: 100000}},
+		{Name: "huge", Height: 64},
+	}
+	paths, err := EncodeLadder(context.Background(), filepath.Join("test_data", "test_video.mp4"),
+		dir, &LadderOptions{
+			Renditions:       renditions,
+			KeyframeInterval: 500 * time.Millisecond,
+			NoUpscale:        true,
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedPaths := []string{filepath.Join(dir, "small.mp4"), filepath.Join(dir, "full.mp4")}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Fatalf("expected paths %v but got %v", expectedPaths, paths)
+	}
+
+	var keyframes [][]time.Duration
+	for _, path := range paths {
+		k, err := GetKeyframes(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyframes = append(keyframes, k)
+	}
+	if len(keyframes[0]) != 4 || !reflect.DeepEqual(keyframes[0], keyframes[1]) {
+		t.Errorf("keyframes are not aligned: %v", keyframes)
+	}
+	if info, err := GetVideoInfo(paths[0]); err != nil {
+		t.Error(err)
+	} else if info.Width != 32 || info.Height != 16 {
+		t.Errorf("unexpected size: %dx%d", info.Width, info.Height)
+	}
+}
+
+func TestDefaultLadder(t *testing.T) {
+	ladder := DefaultLadder()
+	if err := checkRenditionNames(ladder); err != nil {
+		t.Fatal(err)
+	}
+	filtered := filterUpscaling(ladder, &VideoInfo{Width: 1280, Height: 720})
+	var names []string
+	for _, r := range filtered {
+		names = append(names, r.Name)
+	}
+	expected := []string{"240p", "360p", "480p", "720p"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v but got %v", expected, names)
+	}
+}
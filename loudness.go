@@ -0,0 +1,223 @@
+package ffmpego
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Loudness stores EBU R128 loudness measurements of the
+// audio in a file.
+type Loudness struct {
+	// Integrated is the integrated loudness in LUFS.
+	Integrated float64
+
+	// Range is the loudness range in LU.
+	Range float64
+
+	// TruePeak is the maximum true peak in dBTP.
+	TruePeak float64
+
+	// Threshold is the gating threshold in LUFS.
+	Threshold float64
+}
+
+// A LoudnessTarget specifies the desired loudness for
+// loudness normalization.
+//
+// Zero values are replaced with the EBU R128 defaults.
+type LoudnessTarget struct {
+	// Integrated is the target integrated loudness in
+	// LUFS. If 0, -23 is used.
+	Integrated float64
+
+	// Range is the target loudness range in LU.
+	// If 0, 7 is used.
+	Range float64
+
+	// TruePeak is the maximum true peak in dBTP.
+	// If 0, -1 is used.
+	TruePeak float64
+}
+
+func (l *LoudnessTarget) filterParams() string {
+	integrated, lra, truePeak := -23.0, 7.0, -1.0
+	if l.Integrated != 0 {
+		integrated = l.Integrated
+	}
+	if l.Range != 0 {
+		lra = l.Range
+	}
+	if l.TruePeak != 0 {
+		truePeak = l.TruePeak
+	}
+	return fmt.Sprintf("I=%f:LRA=%f:TP=%f", integrated, lra, truePeak)
+}
+
+// MeasureLoudness measures the loudness of the first audio
+// stream in a file, using ffmpeg's loudnorm filter.
+func MeasureLoudness(path string) (loudness *Loudness, err error) {
+	loudness, _, err = measureLoudness(path, &LoudnessTarget{})
+	if err != nil {
+		return nil, errors.Wrap(err, "measure loudness")
+	}
+	return loudness, nil
+}
+
+// NormalizeLoudness copies a file to a new file, changing
+// the volume of the audio so that it reaches the target
+// loudness.
+//
+// This uses two passes: one to measure the audio, and one
+// to apply linear normalization using the measurements.
+// Video and subtitle streams are copied without being
+// re-encoded, except that subtitles are handled as in
+// Remux for MP4 and MOV outputs.
+func NormalizeLoudness(inputPath, outputPath string, target *LoudnessTarget) error {
+	return normalizeLoudness(inputPath, outputPath, target, nil, nil, nil)
+}
+
+// normalizeLoudness is like NormalizeLoudness, but also
+// adds extra inputs after the input file, as well as extra
+// output flags.
+func normalizeLoudness(inputPath, outputPath string, target *LoudnessTarget,
+	encoding *AudioEncoding, extraInputs, extraFlags []string) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "normalize loudness")
+		}
+	}()
+
+	info, err := getMediaInfo(inputPath)
+	if err != nil {
+		return err
+	}
+	stream, err := info.selectStream(StreamTypeAudio, nil)
+	if err != nil {
+		return err
+	}
+	if stream.Audio.Frequency == 0 {
+		return errors.New("could not find frequency in output")
+	}
+	filter, err := loudnessFilter(inputPath, target)
+	if err != nil {
+		return err
+	}
+	args := append([]string{"-y", "-i", inputPath}, extraInputs...)
+	args = append(
+		args,
+		"-map", "0:v?", "-map", fmt.Sprintf("0:%d", stream.Index),
+		"-c:v", "copy",
+	)
+	args = append(args, subtitleCopyArgs(info, outputPath)...)
+	args = append(args, "-filter:a", filter)
+	if encoding == nil || encoding.Frequency == 0 {
+		// The loudnorm filter upsamples audio internally.
+		args = append(args, "-ar", strconv.Itoa(stream.Audio.Frequency))
+	}
+	if encoding != nil {
+		args = append(args, encoding.flags()...)
+	}
+	args = append(args, extraFlags...)
+	_, err = runFFmpeg(context.Background(), append(args, outputPath)...)
+	return err
+}
+
+// loudnessFilter measures a file and creates a filter to
+// normalize it to the target.
+func loudnessFilter(path string, target *LoudnessTarget) (string, error) {
+	measured, offset, err := measureLoudness(path, target)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(
+		"loudnorm=%s:measured_I=%f:measured_LRA=%f:measured_TP=%f:measured_thresh=%f:"+
+			"offset=%f:linear=true",
+		target.filterParams(), measured.Integrated, measured.Range, measured.TruePeak,
+		measured.Threshold, offset,
+	), nil
+}
+
+// measureLoudness measures a file, also returning the gain
+// offset needed to reach the target.
+func measureLoudness(path string, target *LoudnessTarget) (*Loudness, float64, error) {
+	stream, _, err := getAudioStream(path, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	lines, err := runFFmpeg(
+		context.Background(),
+		"-i", path,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-filter:a", "loudnorm="+target.filterParams()+":print_format=json",
+		"-f", "null", "-",
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	return parseLoudnormOutput(lines)
+}
+
+// parseLoudnormOutput parses the JSON summary which the
+// loudnorm filter logs when it is closed.
+//
+// If the filter is logged more than once, for example
+// because the filter graph was reconfigured, only the final
+// summary is used.
+func parseLoudnormOutput(lines []string) (*Loudness, float64, error) {
+	markerIdx := -1
+	for i, line := range lines {
+		if strings.Contains(line, "Parsed_loudnorm") {
+			markerIdx = i
+		}
+	}
+	if markerIdx < 0 {
+		return nil, 0, errors.New("parse loudnorm output: no loudnorm summary found")
+	}
+	var jsonLines []string
+	for _, line := range lines[markerIdx+1:] {
+		if len(jsonLines) == 0 && strings.TrimSpace(line) != "{" {
+			continue
+		}
+		jsonLines = append(jsonLines, line)
+		if strings.TrimSpace(line) == "}" {
+			break
+		}
+	}
+	var values struct {
+		InputI       string `json:"input_i"`
+		InputTP      string `json:"input_tp"`
+		InputLRA     string `json:"input_lra"`
+		InputThresh  string `json:"input_thresh"`
+		TargetOffset string `json:"target_offset"`
+	}
+	if err := json.Unmarshal([]byte(strings.Join(jsonLines, "\n")), &values); err != nil {
+		return nil, 0, errors.Wrap(err, "parse loudnorm output")
+	}
+	var parsed [5]float64
+	for i, s := range []string{values.InputI, values.InputLRA, values.InputTP, values.InputThresh,
+		values.TargetOffset} {
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "parse loudnorm output")
+		}
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			// This happens for silent audio, which cannot be
+			// normalized.
+			return nil, 0, errors.Errorf("parse loudnorm output: measured loudness is %s "+
+				"(is the audio silent?)", s)
+		}
+		parsed[i] = x
+	}
+	return &Loudness{
+		Integrated: parsed[0],
+		Range:      parsed[1],
+		TruePeak:   parsed[2],
+		Threshold:  parsed[3],
+	}, parsed[4], nil
+}
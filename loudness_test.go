@@ -0,0 +1,113 @@
+package ffmpego
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoudness(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-loudness")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inPath := filepath.Join(dir, "in.wav")
+	aw, err := NewAudioWriter(inPath, 44100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := make([]float64, 44100*5)
+	for i := range samples {
+		samples[i] = 0.1 * math.Sin(math.Pi*2*440*float64(i)/44100)
+	}
+	if err := aw.WriteSamples(samples); err != nil {
+		aw.Close()
+		t.Fatal(err)
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := filepath.Join(dir, "out.wav")
+	if err := NormalizeLoudness(inPath, outPath, &LoudnessTarget{Integrated: -16}); err != nil {
+		t.Fatal(err)
+	}
+	loudness, err := MeasureLoudness(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(loudness.Integrated+16) > 1 {
+		t.Errorf("expected loudness of -16 LUFS but got %f", loudness.Integrated)
+	}
+}
+
+func TestParseLoudnormOutput(t *testing.T) {
+	log := `[Parsed_loudnorm_0 @ 0x5582] 
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.10",
+	"input_thresh" : "-38.01",
+	"output_i" : "-24.55",
+	"output_tp" : "-2.00",
+	"output_lra" : "7.80",
+	"output_thresh" : "-34.87",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.55"
+}
+[out#0/null @ 0x5583] video:0kB audio:1kB subtitle:0kB other streams:0kB`
+	loudness, offset, err := parseLoudnormOutput(strings.Split(log, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Loudness{Integrated: -27.61, Range: 18.10, TruePeak: -4.47, Threshold: -38.01}
+	if *loudness != expected {
+		t.Errorf("expected %v but got %v", expected, *loudness)
+	}
+	if offset != 0.55 {
+		t.Errorf("expected offset 0.55 but got %f", offset)
+	}
+}
+
+func TestParseLoudnormOutputMultiple(t *testing.T) {
+	summary := func(integrated, offset string) string {
+		return `{
+	"input_i" : "` + integrated + `",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.10",
+	"input_thresh" : "-38.01",
+	"target_offset" : "` + offset + `"
+}`
+	}
+	log := "[Parsed_loudnorm_0 @ 0x5582] \n" + summary("-30.00", "1.00") + "\n" +
+		"[Parsed_loudnorm_0 @ 0x5590] \n" +
+		"[Parsed_loudnorm_0 @ 0x5590] Some other message\n" +
+		summary("-27.61", "0.55") + "\n" +
+		"[out#0/null @ 0x5583] video:0kB audio:1kB subtitle:0kB other streams:0kB"
+	loudness, offset, err := parseLoudnormOutput(strings.Split(log, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loudness.Integrated != -27.61 || offset != 0.55 {
+		t.Errorf("expected the last summary but got %v (offset %f)", *loudness, offset)
+	}
+}
+
+func TestParseLoudnormOutputSilent(t *testing.T) {
+	log := `[Parsed_loudnorm_0 @ 0x5582] 
+{
+	"input_i" : "-inf",
+	"input_tp" : "-inf",
+	"input_lra" : "0.00",
+	"input_thresh" : "-70.00",
+	"target_offset" : "inf"
+}`
+	if _, _, err := parseLoudnormOutput(strings.Split(log, "\n")); err == nil {
+		t.Error("expected an error for silent audio")
+	}
+}
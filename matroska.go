@@ -0,0 +1,172 @@
+package ffmpego
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// Matroska element IDs, which include their length
+// markers.
+const (
+	mkvEBML               = 0x1A45DFA3
+	mkvEBMLVersion        = 0x4286
+	mkvEBMLReadVersion    = 0x42F7
+	mkvEBMLMaxIDLength    = 0x42F2
+	mkvEBMLMaxSizeLength  = 0x42F3
+	mkvDocType            = 0x4282
+	mkvDocTypeVersion     = 0x4287
+	mkvDocTypeReadVersion = 0x4285
+	mkvSegment            = 0x18538067
+	mkvInfo               = 0x1549A966
+	mkvTimecodeScale      = 0x2AD7B1
+	mkvMuxingApp          = 0x4D80
+	mkvWritingApp         = 0x5741
+	mkvTracks             = 0x1654AE6B
+	mkvTrackEntry         = 0xAE
+	mkvTrackNumber        = 0xD7
+	mkvTrackUID           = 0x73C5
+	mkvTrackType          = 0x83
+	mkvFlagLacing         = 0x9C
+	mkvCodecID            = 0x86
+	mkvVideo              = 0xE0
+	mkvPixelWidth         = 0xB0
+	mkvPixelHeight        = 0xBA
+	mkvColourSpace        = 0x2EB524
+	mkvCluster            = 0x1F43B675
+	mkvTimecode           = 0xE7
+	mkvBlockGroup         = 0xA0
+	mkvBlock              = 0xA1
+	mkvBlockDuration      = 0x9B
+)
+
+// mkvUnknownSize marks an element whose size is not known
+// ahead of time, allowing it to be streamed.
+const mkvUnknownSize = 0x01FFFFFFFFFFFFFF
+
+// A matroskaWriter streams uncompressed rgb24 frames with
+// arbitrary timestamps in a Matroska container.
+//
+// This is used to pass variable frame rate video to
+// ffmpeg, since raw video has no timing information.
+type matroskaWriter struct {
+	w      io.Writer
+	width  int
+	height int
+}
+
+func newMatroskaWriter(w io.Writer, width, height int) *matroskaWriter {
+	return &matroskaWriter{w: w, width: width, height: height}
+}
+
+// WriteHeader writes the file header and track
+// information, and must be called before WriteFrame.
+func (m *matroskaWriter) WriteHeader() error {
+	var buf bytes.Buffer
+	writeEBMLElement(&buf, mkvEBML, ebmlConcat(
+		ebmlUint(mkvEBMLVersion, 1),
+		ebmlUint(mkvEBMLReadVersion, 1),
+		ebmlUint(mkvEBMLMaxIDLength, 4),
+		ebmlUint(mkvEBMLMaxSizeLength, 8),
+		ebmlString(mkvDocType, "matroska"),
+		ebmlUint(mkvDocTypeVersion, 4),
+		ebmlUint(mkvDocTypeReadVersion, 2),
+	))
+	writeEBMLID(&buf, mkvSegment)
+	writeEBMLSize(&buf, mkvUnknownSize)
+	writeEBMLElement(&buf, mkvInfo, ebmlConcat(
+		// Timestamps are stored in microseconds.
+		ebmlUint(mkvTimecodeScale, uint64(time.Microsecond)),
+		ebmlString(mkvMuxingApp, "ffmpego"),
+		ebmlString(mkvWritingApp, "ffmpego"),
+	))
+	writeEBMLElement(&buf, mkvTracks, ebmlElement(mkvTrackEntry, ebmlConcat(
+		ebmlUint(mkvTrackNumber, 1),
+		ebmlUint(mkvTrackUID, 1),
+		ebmlUint(mkvTrackType, 1),
+		ebmlUint(mkvFlagLacing, 0),
+		ebmlString(mkvCodecID, "V_UNCOMPRESSED"),
+		ebmlElement(mkvVideo, ebmlConcat(
+			ebmlUint(mkvPixelWidth, uint64(m.width)),
+			ebmlUint(mkvPixelHeight, uint64(m.height)),
+			// The FourCC which ffmpeg uses for rgb24.
+			ebmlElement(mkvColourSpace, []byte{'R', 'G', 'B', 24}),
+		)),
+	)))
+	_, err := m.w.Write(buf.Bytes())
+	return err
+}
+
+// WriteFrame writes an rgb24 frame with a timestamp and
+// duration.
+//
+// Each frame is stored in its own cluster, so timestamps
+// need not be close together.
+func (m *matroskaWriter) WriteFrame(t, duration time.Duration, data []byte) error {
+	var block bytes.Buffer
+	// Track number 1, a relative timestamp of 0, and no
+	// flags.
+	block.Write([]byte{0x81, 0, 0, 0})
+	block.Write(data)
+
+	group := ebmlConcat(
+		ebmlElement(mkvBlock, block.Bytes()),
+		ebmlUint(mkvBlockDuration, uint64(duration/time.Microsecond)),
+	)
+	var buf bytes.Buffer
+	writeEBMLElement(&buf, mkvCluster, ebmlConcat(
+		ebmlUint(mkvTimecode, uint64(t/time.Microsecond)),
+		ebmlElement(mkvBlockGroup, group),
+	))
+	_, err := m.w.Write(buf.Bytes())
+	return err
+}
+
+func ebmlConcat(elements ...[]byte) []byte {
+	return bytes.Join(elements, nil)
+}
+
+func ebmlElement(id uint32, data []byte) []byte {
+	var buf bytes.Buffer
+	writeEBMLElement(&buf, id, data)
+	return buf.Bytes()
+}
+
+func ebmlUint(id uint32, value uint64) []byte {
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], value)
+	i := 0
+	for i < 7 && data[i] == 0 {
+		i++
+	}
+	return ebmlElement(id, data[i:])
+}
+
+func ebmlString(id uint32, value string) []byte {
+	return ebmlElement(id, []byte(value))
+}
+
+func writeEBMLElement(buf *bytes.Buffer, id uint32, data []byte) {
+	writeEBMLID(buf, id)
+	writeEBMLSize(buf, uint64(len(data))|(1<<56))
+	buf.Write(data)
+}
+
+func writeEBMLID(buf *bytes.Buffer, id uint32) {
+	var data [4]byte
+	binary.BigEndian.PutUint32(data[:], id)
+	i := 0
+	for i < 3 && data[i] == 0 {
+		i++
+	}
+	buf.Write(data[i:])
+}
+
+// writeEBMLSize writes an 8-byte size, which must already
+// include the length marker.
+func writeEBMLSize(buf *bytes.Buffer, size uint64) {
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], size)
+	buf.Write(data[:])
+}
@@ -0,0 +1,66 @@
+package ffmpego
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEBMLEncoding(t *testing.T) {
+	tests := []struct {
+		Actual   []byte
+		Expected []byte
+	}{
+		{
+			ebmlUint(mkvTrackNumber, 1),
+			[]byte{0xD7, 0x01, 0, 0, 0, 0, 0, 0, 1, 1},
+		},
+		{
+			ebmlUint(mkvTimecodeScale, 1000),
+			[]byte{0x2A, 0xD7, 0xB1, 0x01, 0, 0, 0, 0, 0, 0, 2, 0x03, 0xE8},
+		},
+		{
+			ebmlString(mkvCodecID, "V"),
+			[]byte{0x86, 0x01, 0, 0, 0, 0, 0, 0, 1, 'V'},
+		},
+		{
+			ebmlUint(mkvFlagLacing, 0),
+			[]byte{0x9C, 0x01, 0, 0, 0, 0, 0, 0, 1, 0},
+		},
+	}
+	for i, test := range tests {
+		if !bytes.Equal(test.Actual, test.Expected) {
+			t.Errorf("test %d: expected %x but got %x", i, test.Expected, test.Actual)
+		}
+	}
+}
+
+func TestMatroskaWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newMatroskaWriter(&buf, 2, 1)
+	if err := w.WriteHeader(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte{0x1A, 0x45, 0xDF, 0xA3}) {
+		t.Fatal("missing EBML header")
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("V_UNCOMPRESSED")) {
+		t.Error("missing codec ID")
+	}
+
+	buf.Reset()
+	data := []byte{1, 2, 3, 4, 5, 6}
+	if err := w.WriteFrame(2*time.Second, 40*time.Millisecond, data); err != nil {
+		t.Fatal(err)
+	}
+	expected := ebmlElement(mkvCluster, ebmlConcat(
+		ebmlUint(mkvTimecode, 2000000),
+		ebmlElement(mkvBlockGroup, ebmlConcat(
+			ebmlElement(mkvBlock, append([]byte{0x81, 0, 0, 0}, data...)),
+			ebmlUint(mkvBlockDuration, 40000),
+		)),
+	))
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("unexpected cluster: %x", buf.Bytes())
+	}
+}
@@ -0,0 +1,343 @@
+package ffmpego
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A StreamType is the kind of data stored in a stream, as
+// reported by ffmpeg.
+type StreamType string
+
+const (
+	StreamTypeVideo      StreamType = "Video"
+	StreamTypeAudio      StreamType = "Audio"
+	StreamTypeSubtitle   StreamType = "Subtitle"
+	StreamTypeData       StreamType = "Data"
+	StreamTypeAttachment StreamType = "Attachment"
+)
+
+// Dispositions which ffmpeg may report for a stream.
+const (
+	DispositionDefault         = "default"
+	DispositionDub             = "dub"
+	DispositionOriginal        = "original"
+	DispositionCommentary      = "comment"
+	DispositionLyrics          = "lyrics"
+	DispositionKaraoke         = "karaoke"
+	DispositionForced          = "forced"
+	DispositionHearingImpaired = "hearing impaired"
+	DispositionVisualImpaired  = "visual impaired"
+	DispositionCleanEffects    = "clean effects"
+	DispositionAttachedPic     = "attached pic"
+	DispositionCaptions        = "captions"
+	DispositionDescriptions    = "descriptions"
+	DispositionDependent       = "dependent"
+	DispositionMetadata        = "metadata"
+	DispositionStillImage      = "still image"
+)
+
+var allDispositions = []string{
+	DispositionDefault, DispositionDub, DispositionOriginal, DispositionCommentary,
+	DispositionLyrics, DispositionKaraoke, DispositionForced, DispositionHearingImpaired,
+	DispositionVisualImpaired, DispositionCleanEffects, DispositionAttachedPic,
+	DispositionCaptions, DispositionDescriptions, DispositionDependent,
+	DispositionMetadata, DispositionStillImage,
+}
+
+// MediaInfo is information about all of the streams in a
+// media file.
+type MediaInfo struct {
+	// Duration is the length of the file, or 0 if it is
+	// unknown.
+	Duration time.Duration
+
+	// Metadata stores the global tags of the file.
+	Metadata Metadata
+
+	Chapters []*Chapter
+
+	Streams []*StreamInfo
+}
+
+// StreamInfo is information about one stream in a media
+// file.
+type StreamInfo struct {
+	// Index is the index of the stream within the file,
+	// as used by ffmpeg's "-map 0:<index>" syntax.
+	Index int
+
+	Type  StreamType
+	Codec string
+
+	// Profile is the profile of the codec, such as "High"
+	// for H.264, or the empty string if none is reported.
+	Profile string
+
+	// Language is the language tag of the stream, or the
+	// empty string if the stream has none.
+	Language string
+
+	Disposition []string
+
+	// Metadata stores the tags of the stream.
+	//
+	// Newer versions of ffmpeg report the rotation of a
+	// video as side data rather than a tag. In this case,
+	// it is stored as a "rotate" tag anyway, so that
+	// Metadata.Rotation() works for every version.
+	Metadata Metadata
+
+	// Video is set for video streams. Fields which could
+	// not be determined are left zero.
+	Video *VideoInfo
+
+	// Audio is set for audio streams. Fields which could
+	// not be determined are left zero.
+	Audio *AudioInfo
+}
+
+// HasDisposition checks if the stream is marked with the
+// given disposition, such as DispositionDefault.
+func (s *StreamInfo) HasDisposition(disposition string) bool {
+	for _, d := range s.Disposition {
+		if d == disposition {
+			return true
+		}
+	}
+	return false
+}
+
+// A StreamSelector chooses which stream of a media file to
+// use when a file contains more than one stream of a type.
+//
+// A StreamSelector is only called on streams of the type
+// being requested, and it should return true for streams
+// which are acceptable.
+type StreamSelector func(s *StreamInfo) bool
+
+// StreamWithIndex selects the stream at the given index
+// within the file.
+func StreamWithIndex(index int) StreamSelector {
+	return func(s *StreamInfo) bool {
+		return s.Index == index
+	}
+}
+
+// StreamWithLanguage selects streams with a language tag,
+// such as "eng".
+func StreamWithLanguage(language string) StreamSelector {
+	return func(s *StreamInfo) bool {
+		return s.Language == language
+	}
+}
+
+// StreamWithDisposition selects streams which are marked
+// with a disposition, such as DispositionCommentary.
+func StreamWithDisposition(disposition string) StreamSelector {
+	return func(s *StreamInfo) bool {
+		return s.HasDisposition(disposition)
+	}
+}
+
+// GetMediaInfo gets information about every stream in a
+// media file.
+func GetMediaInfo(path string) (info *MediaInfo, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "get media info")
+		}
+	}()
+	return getMediaInfo(path)
+}
+
+func getMediaInfo(path string) (*MediaInfo, error) {
+	// Make sure file exists so we can give a clean error
+	// message in this case, instead of depending on ffmpeg.
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	lines, err := infoOutputLines(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseMediaInfo(lines)
+}
+
+func parseMediaInfo(lines []string) (*MediaInfo, error) {
+	result := &MediaInfo{Metadata: Metadata{}}
+	var metadata metadataParser
+	var stream *StreamInfo
+
+	// Tags belong to the most recent file, chapter, or
+	// stream header.
+	target := result.Metadata
+	var chapterMetadata []Metadata
+
+	durationExp := regexp.MustCompilePOSIX("^ *Duration: ([0-9]+):([0-9]+):([0-9\\.]+),")
+	streamExp := regexp.MustCompilePOSIX("^ *Stream #[0-9]+:([0-9]+)(\\[[^]]*\\])?(\\(([^)]*)\\))?: " +
+		"([A-Za-z]+): (.*)$")
+	// The profile is the first parenthesized part of the
+	// codec, as opposed to tags like "(avc1 / 0x31637661)".
+	profileExp := regexp.MustCompilePOSIX(" \\(([^()/]+)\\)")
+	rotationExp := regexp.MustCompilePOSIX("displaymatrix: rotation of (-?[0-9\\.]+) degrees")
+	chapterExp := regexp.MustCompilePOSIX("^ *Chapter #[0-9]+:[0-9]+: start (-?[0-9\\.]+), " +
+		"end (-?[0-9\\.]+)")
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if metadata.Parse(line) {
+			continue
+		}
+		if metadata.Start(line, target) {
+			continue
+		}
+		if match := chapterExp.FindStringSubmatch(line); match != nil {
+			start, err := parseSeconds(match[1])
+			if err != nil {
+				return nil, errors.Wrap(err, "parse chapter")
+			}
+			end, err := parseSeconds(match[2])
+			if err != nil {
+				return nil, errors.Wrap(err, "parse chapter")
+			}
+			result.Chapters = append(result.Chapters, &Chapter{Start: start, End: end})
+			target = Metadata{}
+			chapterMetadata = append(chapterMetadata, target)
+			continue
+		}
+		if match := rotationExp.FindStringSubmatch(line); match != nil && stream != nil {
+			if _, ok := stream.Metadata["rotate"]; !ok {
+				// The display matrix rotates counterclockwise.
+				degrees, _ := strconv.ParseFloat(match[1], 64)
+				stream.Metadata["rotate"] = strconv.Itoa(int(math.Round(-degrees)))
+			}
+			continue
+		}
+		if match := durationExp.FindStringSubmatch(line); match != nil {
+			hours, _ := strconv.Atoi(match[1])
+			minutes, _ := strconv.Atoi(match[2])
+			seconds, err := strconv.ParseFloat(match[3], 64)
+			if err != nil {
+				return nil, errors.Wrap(err, "parse duration")
+			}
+			result.Duration = time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+				time.Duration(seconds*float64(time.Second))
+			continue
+		}
+		match := streamExp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		index, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, errors.Wrap(err, "parse stream index")
+		}
+		stream = &StreamInfo{
+			Index:    index,
+			Type:     StreamType(match[5]),
+			Language: match[4],
+			Metadata: Metadata{},
+		}
+		target = stream.Metadata
+		desc := match[6]
+		codecDesc := strings.Split(desc, ",")[0]
+		if fields := strings.Fields(codecDesc); len(fields) > 0 {
+			stream.Codec = fields[0]
+		}
+		if match := profileExp.FindStringSubmatch(codecDesc); match != nil {
+			stream.Profile = match[1]
+		}
+		stream.Disposition = parseDispositions(desc)
+		switch stream.Type {
+		case StreamTypeVideo:
+			stream.Video, err = parseVideoStream(desc)
+		case StreamTypeAudio:
+			stream.Audio, err = parseAudioStream(desc)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Streams = append(result.Streams, stream)
+	}
+	for i, m := range chapterMetadata {
+		result.Chapters[i].Title = m["title"]
+	}
+
+	return result, nil
+}
+
+// parseDispositions finds the parenthesized dispositions at
+// the end of a stream description.
+func parseDispositions(desc string) []string {
+	var result []string
+	desc = strings.TrimSpace(desc)
+	for strings.HasSuffix(desc, ")") {
+		idx := strings.LastIndex(desc, " (")
+		if idx < 0 {
+			break
+		}
+		name := desc[idx+2 : len(desc)-1]
+		var known bool
+		for _, d := range allDispositions {
+			if d == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			break
+		}
+		result = append([]string{name}, result...)
+		desc = strings.TrimSpace(desc[:idx])
+	}
+	return result
+}
+
+// selectStream finds the first stream of the given type
+// which matches the selector.
+//
+// If the selector is nil, then the first stream of the
+// given type is used, skipping attached pictures such as
+// album artwork.
+func (m *MediaInfo) selectStream(t StreamType, selector StreamSelector) (*StreamInfo, error) {
+	for _, s := range m.Streams {
+		if s.Type != t {
+			continue
+		}
+		if selector == nil {
+			if !s.HasDisposition(DispositionAttachedPic) {
+				return s, nil
+			}
+		} else if selector(s) {
+			return s, nil
+		}
+	}
+	if selector == nil {
+		return nil, errors.Errorf("no %s stream found", strings.ToLower(string(t)))
+	}
+	return nil, errors.Errorf("no %s stream matches selector", strings.ToLower(string(t)))
+}
+
+// formatSeconds formats a duration as a number of seconds
+// for an ffmpeg argument.
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%f", d.Seconds())
+}
+
+// parseSeconds parses a number of seconds, as logged by
+// ffmpeg.
+func parseSeconds(s string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "parse timestamp")
+	}
+	return time.Duration(math.Round(seconds * float64(time.Second))), nil
+}
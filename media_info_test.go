@@ -0,0 +1,120 @@
+package ffmpego
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMultiStreamOutput = `Input #0, matroska,webm, from 'multi.mkv':
+  Metadata:
+    ENCODER         : Lavf58.29.100
+  Duration: 00:00:02.00, start: 0.000000, bitrate: 91 kb/s
+    Stream #0:0: Video: h264 (High), yuv420p(progressive), 64x32 [SAR 1:1 DAR 2:1], 12 fps, 12 tbr, 1k tbn, 24 tbc (default)
+    Metadata:
+      DURATION        : 00:00:02.000000000
+    Stream #0:1(eng): Video: h264 (High), yuv420p(progressive), 128x64 [SAR 1:1 DAR 2:1], 24 fps, 24 tbr, 1k tbn, 48 tbc
+    Stream #0:2(eng): Audio: aac (LC), 44100 Hz, stereo, fltp (default)
+    Stream #0:3[0x1100](jpn): Audio: vorbis, 8000 Hz, mono, fltp (comment) (forced)
+    Stream #0:4(eng): Subtitle: subrip
+At least one output file must be specified`
+
+func TestMediaInfo(t *testing.T) {
+	info, err := GetMediaInfo(filepath.Join("test_data", "test_video.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Streams) != 1 {
+		t.Fatalf("expected 1 stream but got %d", len(info.Streams))
+	}
+	stream := info.Streams[0]
+	if stream.Type != StreamTypeVideo || stream.Video == nil {
+		t.Fatalf("unexpected stream: %#v", stream)
+	}
+	if stream.Video.Width != 64 || stream.Video.Height != 32 {
+		t.Errorf("unexpected size: %dx%d", stream.Video.Width, stream.Video.Height)
+	}
+}
+
+func TestParseMediaInfo(t *testing.T) {
+	info, err := parseMediaInfo(strings.Split(testMultiStreamOutput, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Duration != 2*time.Second {
+		t.Errorf("expected duration 2s but got %v", info.Duration)
+	}
+	if len(info.Streams) != 5 {
+		t.Fatalf("expected 5 streams but got %d", len(info.Streams))
+	}
+
+	types := []StreamType{StreamTypeVideo, StreamTypeVideo, StreamTypeAudio, StreamTypeAudio,
+		StreamTypeSubtitle}
+	languages := []string{"", "eng", "eng", "jpn", "eng"}
+	codecs := []string{"h264", "h264", "aac", "vorbis", "subrip"}
+	for i, s := range info.Streams {
+		if s.Index != i {
+			t.Errorf("stream %d: bad index %d", i, s.Index)
+		}
+		if s.Type != types[i] {
+			t.Errorf("stream %d: expected type %s but got %s", i, types[i], s.Type)
+		}
+		if s.Language != languages[i] {
+			t.Errorf("stream %d: expected language %q but got %q", i, languages[i], s.Language)
+		}
+		if s.Codec != codecs[i] {
+			t.Errorf("stream %d: expected codec %q but got %q", i, codecs[i], s.Codec)
+		}
+	}
+
+	if v := info.Streams[1].Video; v.Width != 128 || v.Height != 64 || v.FPS != 24 {
+		t.Errorf("bad video info: %#v", v)
+	}
+	if v := info.Streams[1].Video; v.PixelFormat != "yuv420p" || v.TimeScale != 1000 {
+		t.Errorf("bad video format: %#v", v)
+	}
+	if a := info.Streams[3].Audio; a.Frequency != 8000 || a.Channels != 1 || a.ChannelLayout != "mono" {
+		t.Errorf("bad audio info: %#v", a)
+	}
+	if a := info.Streams[2].Audio; a.Channels != 2 || a.ChannelLayout != "stereo" {
+		t.Errorf("bad audio info: %#v", a)
+	}
+	profiles := []string{"High", "High", "LC", "", ""}
+	for i, s := range info.Streams {
+		if s.Profile != profiles[i] {
+			t.Errorf("stream %d: expected profile %q but got %q", i, profiles[i], s.Profile)
+		}
+	}
+	if d := info.Streams[3].Disposition; len(d) != 2 || d[0] != DispositionCommentary ||
+		d[1] != DispositionForced {
+		t.Errorf("bad dispositions: %v", d)
+	}
+
+	selectorTests := []struct {
+		Type     StreamType
+		Selector StreamSelector
+		Index    int
+	}{
+		{StreamTypeVideo, nil, 0},
+		{StreamTypeVideo, StreamWithIndex(1), 1},
+		{StreamTypeVideo, StreamWithLanguage("eng"), 1},
+		{StreamTypeAudio, nil, 2},
+		{StreamTypeAudio, StreamWithLanguage("jpn"), 3},
+		{StreamTypeAudio, StreamWithDisposition(DispositionCommentary), 3},
+		{StreamTypeAudio, StreamWithDisposition(DispositionDefault), 2},
+		{StreamTypeAudio, StreamWithIndex(1), -1},
+	}
+	for i, test := range selectorTests {
+		stream, err := info.selectStream(test.Type, test.Selector)
+		if test.Index < 0 {
+			if err == nil {
+				t.Errorf("test %d: expected error", i)
+			}
+		} else if err != nil {
+			t.Errorf("test %d: %s", i, err)
+		} else if stream.Index != test.Index {
+			t.Errorf("test %d: expected stream %d but got %d", i, test.Index, stream.Index)
+		}
+	}
+}
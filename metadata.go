@@ -0,0 +1,143 @@
+package ffmpego
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata stores the tags of a file or stream, such as
+// "title", "artist", "language" or "creation_time".
+//
+// Tag names are passed to and from ffmpeg unchanged, so
+// their capitalization depends on the container.
+type Metadata map[string]string
+
+// CreationTime parses the "creation_time" tag.
+//
+// The second return value is false if the tag is missing
+// or cannot be parsed.
+func (m Metadata) CreationTime() (time.Time, bool) {
+	value, ok := m["creation_time"]
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// SetCreationTime sets the "creation_time" tag in the
+// format used by ffmpeg.
+func (m Metadata) SetCreationTime(t time.Time) {
+	m["creation_time"] = t.UTC().Format("2006-01-02T15:04:05.000000Z")
+}
+
+// Rotation parses the "rotate" tag, which is the number of
+// degrees that a video should be rotated clockwise when it
+// is displayed.
+//
+// The result is in the range [0, 360). The second return
+// value is false if the tag is missing or cannot be
+// parsed.
+func (m Metadata) Rotation() (int, bool) {
+	value, ok := m["rotate"]
+	if !ok {
+		return 0, false
+	}
+	degrees, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, false
+	}
+	return ((int(math.Round(degrees)) % 360) + 360) % 360, true
+}
+
+// metadataFlags creates output flags which set the tags
+// matching a metadata specifier, such as "" for global
+// metadata or ":s:v:0" for the first video stream.
+func metadataFlags(specifier string, m Metadata) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var flags []string
+	for _, key := range keys {
+		flags = append(flags, "-metadata"+specifier, key+"="+m[key])
+	}
+	return flags
+}
+
+// outputMetadataFlags creates output flags for global tags
+// as well as tags for the first video and audio streams.
+func outputMetadataFlags(global, video, audio Metadata) []string {
+	flags := metadataFlags("", global)
+	flags = append(flags, metadataFlags(":s:v:0", video)...)
+	return append(flags, metadataFlags(":s:a:0", audio)...)
+}
+
+// metadataParser collects the tags from the indented
+// "Metadata:" sections of ffmpeg's output.
+type metadataParser struct {
+	target Metadata
+	indent int
+	key    string
+}
+
+// Start begins a new section if the line is a section
+// header, storing the tags in target.
+//
+// It returns false if the line is not a section header.
+func (m *metadataParser) Start(line string, target Metadata) bool {
+	if strings.TrimSpace(line) != "Metadata:" {
+		return false
+	}
+	m.target = target
+	m.indent = lineIndent(line)
+	m.key = ""
+	return true
+}
+
+// Parse adds a tag from a line in the current section.
+//
+// It returns false and ends the section if the line is not
+// part of it.
+func (m *metadataParser) Parse(line string) bool {
+	if m.target == nil {
+		return false
+	}
+	if lineIndent(line) <= m.indent || strings.TrimSpace(line) == "" {
+		m.target = nil
+		return false
+	}
+	idx := strings.Index(line, ":")
+	if idx < 0 {
+		m.target = nil
+		return false
+	}
+	key := strings.TrimSpace(line[:idx])
+	value := line[idx+1:]
+	if strings.HasPrefix(value, " ") {
+		value = value[1:]
+	}
+	if key == "" {
+		// Values with newlines continue on lines with no
+		// key.
+		if m.key != "" {
+			m.target[m.key] += "\n" + value
+		}
+		return true
+	}
+	m.key = key
+	m.target[key] = value
+	return true
+}
+
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
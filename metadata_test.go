@@ -0,0 +1,144 @@
+package ffmpego
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMetadataOutput = `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'tagged.mp4':
+  Metadata:
+    major_brand     : isom
+    title           : My Video
+    comment         : first line
+                    : second line
+    creation_time   : 2021-03-04T05:06:07.000000Z
+  Duration: 00:00:02.00, start: 0.000000, bitrate: 31 kb/s
+  Stream #0:0[0x1](eng): Video: h264 (High) (avc1 / 0x31637661), yuv420p(progressive), 64x32, 12 fps, 12 tbr, 12288 tbn (default)
+    Metadata:
+      handler_name    : VideoHandler
+    Side data:
+      displaymatrix: rotation of -90.00 degrees
+  Stream #0:1[0x2](und): Audio: aac (LC) (mp4a / 0x6134706D), 8000 Hz, mono, fltp, 12 kb/s (default)
+    Metadata:
+      handler_name    : SoundHandler
+      rotate          : 180
+At least one output file must be specified`
+
+func TestParseMetadata(t *testing.T) {
+	info, err := parseMediaInfo(strings.Split(testMetadataOutput, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Metadata{
+		"major_brand":   "isom",
+		"title":         "My Video",
+		"comment":       "first line\nsecond line",
+		"creation_time": "2021-03-04T05:06:07.000000Z",
+	}
+	if !reflect.DeepEqual(info.Metadata, expected) {
+		t.Errorf("unexpected global metadata: %#v", info.Metadata)
+	}
+	if info.Duration != 2*time.Second {
+		t.Errorf("unexpected duration: %v", info.Duration)
+	}
+	if len(info.Streams) != 2 {
+		t.Fatalf("expected 2 streams but got %d", len(info.Streams))
+	}
+	expected = Metadata{"handler_name": "VideoHandler", "rotate": "90"}
+	if !reflect.DeepEqual(info.Streams[0].Metadata, expected) {
+		t.Errorf("unexpected video metadata: %#v", info.Streams[0].Metadata)
+	}
+	expected = Metadata{"handler_name": "SoundHandler", "rotate": "180"}
+	if !reflect.DeepEqual(info.Streams[1].Metadata, expected) {
+		t.Errorf("unexpected audio metadata: %#v", info.Streams[1].Metadata)
+	}
+
+	creationTime, ok := info.Metadata.CreationTime()
+	if !ok || !creationTime.Equal(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)) {
+		t.Errorf("unexpected creation time: %v %v", creationTime, ok)
+	}
+	if rotation, ok := info.Streams[0].Metadata.Rotation(); !ok || rotation != 90 {
+		t.Errorf("unexpected rotation: %d %v", rotation, ok)
+	}
+}
+
+func TestMetadataHelpers(t *testing.T) {
+	m := Metadata{}
+	if _, ok := m.CreationTime(); ok {
+		t.Error("unexpected creation time")
+	}
+	if _, ok := m.Rotation(); ok {
+		t.Error("unexpected rotation")
+	}
+	date := time.Date(2020, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	m.SetCreationTime(date)
+	if m["creation_time"] != "2020-01-02T03:04:05.600000Z" {
+		t.Errorf("unexpected creation_time: %s", m["creation_time"])
+	}
+	if actual, ok := m.CreationTime(); !ok || !actual.Equal(date) {
+		t.Errorf("unexpected creation time: %v", actual)
+	}
+	m["rotate"] = "-90"
+	if rotation, ok := m.Rotation(); !ok || rotation != 270 {
+		t.Errorf("unexpected rotation: %d", rotation)
+	}
+}
+
+func TestOutputMetadataFlags(t *testing.T) {
+	flags := outputMetadataFlags(Metadata{"title": "x", "artist": "y"}, nil,
+		Metadata{"language": "eng"})
+	expected := []string{
+		"-metadata", "artist=y",
+		"-metadata", "title=x",
+		"-metadata:s:a:0", "language=eng",
+	}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("unexpected flags: %v", flags)
+	}
+}
+
+func TestVideoWriterMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-video-writer-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outPath := filepath.Join(dir, "out.mkv")
+	vw, err := NewVideoWriterWithOptions(outPath, 50, 50, 12, &VideoWriterOptions{
+		Metadata:      Metadata{"title": "Test Title", "custom_key": "custom value"},
+		VideoMetadata: Metadata{"language": "fra"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 12; i++ {
+		if err := vw.WriteFrame(image.NewGray(image.Rect(0, 0, 50, 50))); err != nil {
+			vw.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := vw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := GetMediaInfo(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Metadata["title"] != "Test Title" {
+		t.Errorf("unexpected title: %q", info.Metadata["title"])
+	}
+	if info.Metadata["CUSTOM_KEY"] != "custom value" && info.Metadata["custom_key"] != "custom value" {
+		t.Errorf("unexpected metadata: %v", info.Metadata)
+	}
+	if info.Streams[0].Language != "fra" {
+		t.Errorf("unexpected language: %q", info.Streams[0].Language)
+	}
+}
@@ -0,0 +1,268 @@
+package ffmpego
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A PackageFormat is a format for adaptive streaming.
+type PackageFormat int
+
+const (
+	// PackageHLS produces an HLS master playlist named
+	// "master.m3u8", along with a media playlist and
+	// segments for every rendition in a subdirectory named
+	// after the rendition.
+	PackageHLS PackageFormat = iota
+
+	// PackageDASH produces a DASH manifest named
+	// "manifest.mpd", along with segments for every
+	// rendition in the same directory.
+	PackageDASH
+)
+
+// A Rendition is one encoding of a video in an adaptive
+// bitrate ladder.
+type Rendition struct {
+	// Name identifies the rendition, for example in
+	// playlists and file names, as in "720p".
+	Name string
+
+	// Width and Height are the dimensions of the rendition.
+	// If one of them is 0, it is chosen to preserve the
+	// aspect ratio. If both are 0, the video is not scaled.
+	Width  int
+	Height int
+
+	// Video configures the encoder.
+	// If nil, DefaultVideoEncoding() is used.
+	//
+	// A Bitrate should typically be set, since players use
+	// it to choose between renditions.
+	Video *VideoEncoding
+}
+
+// PackageOptions configures how Package creates a stream.
+type PackageOptions struct {
+	Format PackageFormat
+
+	// SegmentDuration is the target duration of each
+	// segment. If 0, 6 seconds is used.
+	//
+	// Keyframes are placed at every multiple of the segment
+	// duration, so segments are aligned across renditions.
+	SegmentDuration time.Duration
+
+	// Renditions are the encodings of the video.
+	// If empty, one rendition is created at the original
+	// size using DefaultVideoEncoding().
+	Renditions []*Rendition
+
+	// Audio configures the audio encoder. Audio is encoded
+	// once and shared by all of the renditions.
+	// If nil, 128 kb/s AAC is used.
+	Audio *AudioEncoding
+
+	// FMP4 causes HLS segments to be stored as fragmented
+	// MP4 rather than MPEG-TS.
+	FMP4 bool
+}
+
+// Package encodes a media file for adaptive streaming,
+// writing a manifest and segments for every rendition to
+// a directory.
+//
+// All of the renditions are encoded by one ffmpeg process,
+// which decodes the input only once.
+func Package(ctx context.Context, input, dir string, opts *PackageOptions) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "package")
+		}
+	}()
+
+	renditions := opts.Renditions
+	if len(renditions) == 0 {
+		renditions = []*Rendition{{Name: "default"}}
+	}
+	if err := checkRenditionNames(renditions); err != nil {
+		return err
+	}
+	segmentDuration := opts.SegmentDuration
+	if segmentDuration == 0 {
+		segmentDuration = 6 * time.Second
+	}
+	audioEncoding := opts.Audio
+	if audioEncoding == nil {
+		audioEncoding = &AudioEncoding{Codec: "aac", Bitrate: 128000}
+	}
+
+	info, err := getMediaInfo(input)
+	if err != nil {
+		return err
+	}
+	video, err := info.selectStream(StreamTypeVideo, nil)
+	if err != nil {
+		return err
+	}
+	audio, _ := selectOptionalStream(info, StreamTypeAudio, nil)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	args := []string{"-y", "-i", input}
+	args = append(args, renditionArgs(video.Index, renditions, segmentDuration)...)
+	if audio != nil {
+		args = append(args, "-map", fmt.Sprintf("0:%d", audio.Index))
+		args = append(args, audioEncoding.flags()...)
+	}
+
+	switch opts.Format {
+	case PackageHLS:
+		for _, r := range renditions {
+			if err := os.MkdirAll(filepath.Join(dir, r.Name), 0755); err != nil {
+				return err
+			}
+		}
+		if audio != nil {
+			if err := os.MkdirAll(filepath.Join(dir, "audio"), 0755); err != nil {
+				return err
+			}
+		}
+		args = append(args, hlsArgs(dir, renditions, audio != nil, segmentDuration, opts.FMP4)...)
+	case PackageDASH:
+		args = append(args, dashArgs(dir, audio != nil, segmentDuration)...)
+	default:
+		panic(fmt.Sprintf("unknown package format: %d", opts.Format))
+	}
+	_, err = runFFmpeg(ctx, args...)
+	return err
+}
+
+func checkRenditionNames(renditions []*Rendition) error {
+	names := map[string]bool{"audio": true}
+	for _, r := range renditions {
+		if r.Name == "" || strings.ContainsAny(r.Name, `/\ ,:%$`) {
+			return errors.New("invalid rendition name: " + r.Name)
+		}
+		if names[r.Name] {
+			return errors.New("duplicate or reserved rendition name: " + r.Name)
+		}
+		names[r.Name] = true
+	}
+	return nil
+}
+
+// renditionArgs creates the filters and output flags for
+// encoding one video stream as every rendition, with
+// keyframes at every multiple of the segment duration.
+//
+// The renditions become output video streams with the
+// same indices as in the list.
+func renditionArgs(streamIndex int, renditions []*Rendition, segmentDuration time.Duration) []string {
+	args := []string{"-filter_complex", renditionFilter(streamIndex, renditions)}
+	for i, r := range renditions {
+		args = append(args, "-map", fmt.Sprintf("[r%d]", i))
+		args = append(args, r.encoding().streamFlags(i)...)
+	}
+	return append(args, alignedKeyframeArgs(segmentDuration)...)
+}
+
+// alignedKeyframeArgs forces keyframes at every multiple of
+// an interval in every output video stream.
+func alignedKeyframeArgs(interval time.Duration) []string {
+	return []string{
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%f)", interval.Seconds()),
+	}
+}
+
+func (r *Rendition) encoding() *VideoEncoding {
+	if r.Video == nil {
+		return DefaultVideoEncoding()
+	}
+	return r.Video
+}
+
+// renditionFilter creates a filtergraph which decodes a
+// video stream once and scales it for every rendition,
+// producing outputs [r0], [r1], etc.
+func renditionFilter(streamIndex int, renditions []*Rendition) string {
+	var splitOutputs string
+	var parts []string
+	for i, r := range renditions {
+		splitOutputs += fmt.Sprintf("[s%d]", i)
+		encoding := r.encoding()
+		if r.Width == 0 && r.Height == 0 {
+			parts = append(parts, fmt.Sprintf("[s%d]null[r%d]", i, i))
+			continue
+		}
+		auto := -1
+		if encoding.needsEvenSize() {
+			auto = -2
+		}
+		width, height := r.Width, r.Height
+		if width == 0 {
+			width = auto
+		}
+		if height == 0 {
+			height = auto
+		}
+		parts = append(parts, fmt.Sprintf("[s%d]scale=%d:%d[r%d]", i, width, height, i))
+	}
+	split := fmt.Sprintf("[0:%d]split=%d%s", streamIndex, len(renditions), splitOutputs)
+	return strings.Join(append([]string{split}, parts...), ";")
+}
+
+func hlsArgs(dir string, renditions []*Rendition, hasAudio bool, segmentDuration time.Duration,
+	fmp4 bool) []string {
+	var streamMap []string
+	if hasAudio {
+		streamMap = append(streamMap, "a:0,agroup:audio,name:audio")
+	}
+	for i, r := range renditions {
+		entry := fmt.Sprintf("v:%d,name:%s", i, r.Name)
+		if hasAudio {
+			entry += ",agroup:audio"
+		}
+		streamMap = append(streamMap, entry)
+	}
+	segmentName := "segment_%05d.ts"
+	args := []string{
+		"-f", "hls",
+		"-hls_time", fmt.Sprintf("%f", segmentDuration.Seconds()),
+		"-hls_playlist_type", "vod",
+		"-master_pl_name", "master.m3u8",
+		"-var_stream_map", strings.Join(streamMap, " "),
+	}
+	if fmp4 {
+		segmentName = "segment_%05d.m4s"
+		args = append(args, "-hls_segment_type", "fmp4", "-hls_fmp4_init_filename", "init.mp4")
+	}
+	return append(
+		args,
+		"-hls_segment_filename", filepath.Join(dir, "%v", segmentName),
+		filepath.Join(dir, "%v", "index.m3u8"),
+	)
+}
+
+func dashArgs(dir string, hasAudio bool, segmentDuration time.Duration) []string {
+	adaptationSets := "id=0,streams=v"
+	if hasAudio {
+		adaptationSets += " id=1,streams=a"
+	}
+	return []string{
+		"-f", "dash",
+		"-seg_duration", fmt.Sprintf("%f", segmentDuration.Seconds()),
+		"-use_template", "1",
+		"-use_timeline", "1",
+		"-adaptation_sets", adaptationSets,
+		filepath.Join(dir, "manifest.mpd"),
+	}
+}
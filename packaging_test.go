@@ -0,0 +1,108 @@
+package ffmpego
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-package")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	renditions := []*Rendition{
+		{Name: "full", Video: &VideoEncoding{Codec: "libx264", Bitrate: 200000}},
+		{Name: "half", Height: 16, Video: &VideoEncoding{Codec: "libx264", Bitrate: 100000}},
+	}
+	inPath := filepath.Join("test_data", "test_video.mp4")
+
+	hlsDir := filepath.Join(dir, "hls")
+	err = Package(context.Background(), inPath, hlsDir, &PackageOptions{
+		Format:          PackageHLS,
+		SegmentDuration: time.Second,
+		Renditions:      renditions,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, err := ioutil.ReadFile(filepath.Join(hlsDir, "master.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"full/index.m3u8", "half/index.m3u8"} {
+		if !strings.Contains(string(master), name) {
+			t.Errorf("master playlist does not reference %s", name)
+		}
+		if _, err := os.Stat(filepath.Join(hlsDir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+	segments, _ := filepath.Glob(filepath.Join(hlsDir, "half", "*.ts"))
+	if len(segments) != 2 {
+		t.Errorf("expected 2 segments but got %d", len(segments))
+	}
+
+	dashDir := filepath.Join(dir, "dash")
+	err = Package(context.Background(), inPath, dashDir, &PackageOptions{
+		Format:     PackageDASH,
+		Renditions: renditions,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dashDir, "manifest.mpd")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRenditionArgs(t *testing.T) {
+	renditions := []*Rendition{
+		{Name: "720p", Height: 720, Video: &VideoEncoding{Codec: "libx264", Bitrate: 3000000}},
+		{Name: "source"},
+	}
+	actual := renditionArgs(2, renditions, 4*time.Second)
+	expected := []string{
+		"-filter_complex", "[0:2]split=2[s0][s1];[s0]scale=-1:720[r0];[s1]null[r1]",
+		"-map", "[r0]", "-c:v:0", "libx264", "-b:v:0", "3000000",
+		"-map", "[r1]", "-c:v:1", "libx264", "-preset:v:1", "fast", "-crf:v:1", "18",
+		"-pix_fmt:v:1", "yuv420p",
+		"-force_key_frames", "expr:gte(t,n_forced*4.000000)",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+
+	if err := checkRenditionNames([]*Rendition{{Name: "a"}, {Name: "a"}}); err == nil {
+		t.Error("expected error for duplicate names")
+	}
+	if err := checkRenditionNames([]*Rendition{{Name: "a b"}}); err == nil {
+		t.Error("expected error for invalid name")
+	}
+}
+
+func TestHLSArgs(t *testing.T) {
+	renditions := []*Rendition{{Name: "720p"}, {Name: "360p"}}
+	actual := hlsArgs("out", renditions, true, 6*time.Second, true)
+	expected := []string{
+		"-f", "hls",
+		"-hls_time", "6.000000",
+		"-hls_playlist_type", "vod",
+		"-master_pl_name", "master.m3u8",
+		"-var_stream_map", "a:0,agroup:audio,name:audio v:0,name:720p,agroup:audio " +
+			"v:1,name:360p,agroup:audio",
+		"-hls_segment_type", "fmp4", "-hls_fmp4_init_filename", "init.mp4",
+		"-hls_segment_filename", filepath.Join("out", "%v", "segment_%05d.m4s"),
+		filepath.Join("out", "%v", "index.m3u8"),
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
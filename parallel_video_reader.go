@@ -0,0 +1,289 @@
+package ffmpego
+
+import (
+	"context"
+	"image"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ParallelVideoReaderOptions configures how a video is
+// split up for parallel decoding.
+type ParallelVideoReaderOptions struct {
+	// Stream chooses which video stream to decode.
+	// If nil, the first video stream is used.
+	Stream StreamSelector
+
+	// Workers is the maximum number of ffmpeg processes to
+	// run at once. If 0 or negative, runtime.GOMAXPROCS(0)
+	// is used.
+	Workers int
+
+	// ChunkFrames is the minimum number of frames to decode
+	// in each ffmpeg process. Chunks always start at a
+	// keyframe, so they may be longer than this.
+	//
+	// If 0 or negative, the video is split into roughly 4
+	// chunks per worker.
+	ChunkFrames int
+
+	// BufferFrames is the number of decoded frames which
+	// may be buffered for each chunk that is decoded ahead
+	// of the current one when reading frames in order.
+	// If 0 or negative, 128 is used.
+	//
+	// Larger buffers allow more decoding to happen at once,
+	// at the expense of memory.
+	BufferFrames int
+}
+
+// A ParallelVideoReader decodes a video file using multiple
+// ffmpeg processes at once.
+//
+// The video is split into keyframe-aligned chunks which are
+// decoded separately. Frames are decoded exactly as they
+// are stored in the file, without any frame rate
+// conversion.
+type ParallelVideoReader struct {
+	info   *VideoInfo
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	chunks chan (<-chan chunkFrame)
+
+	current  <-chan chunkFrame
+	finished bool
+}
+
+type chunkFrame struct {
+	Index int
+	Image image.Image
+	Err   error
+}
+
+// NewParallelVideoReader creates a ParallelVideoReader
+// which reads frames in order.
+func NewParallelVideoReader(path string, opts *ParallelVideoReaderOptions) (*ParallelVideoReader, error) {
+	plan, err := planParallelDecode(path, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "read video in parallel")
+	}
+
+	bufferSize := opts.BufferFrames
+	if bufferSize <= 0 {
+		bufferSize = 128
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	res := &ParallelVideoReader{
+		info:   plan.Info,
+		cancel: cancel,
+		chunks: make(chan (<-chan chunkFrame), plan.Workers),
+	}
+	res.wg.Add(1)
+	go func() {
+		defer res.wg.Done()
+		defer close(res.chunks)
+		sem := make(chan struct{}, plan.Workers)
+		for _, chunk := range plan.Chunks {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			ch := make(chan chunkFrame, bufferSize)
+			res.chunks <- ch
+			res.wg.Add(1)
+			go func(chunk *videoChunk) {
+				defer res.wg.Done()
+				defer func() {
+					<-sem
+				}()
+				defer close(ch)
+				plan.Decode(ctx, chunk, func(f chunkFrame) bool {
+					select {
+					case ch <- f:
+						return true
+					case <-ctx.Done():
+						return false
+					}
+				})
+			}(chunk)
+		}
+	}()
+	return res, nil
+}
+
+// VideoInfo gets information about the current video.
+func (p *ParallelVideoReader) VideoInfo() *VideoInfo {
+	return p.info
+}
+
+// ReadFrame reads the next frame from the video.
+//
+// If the video is finished decoding, nil will be returned
+// along with io.EOF.
+func (p *ParallelVideoReader) ReadFrame() (image.Image, error) {
+	for !p.finished {
+		if p.current == nil {
+			var ok bool
+			p.current, ok = <-p.chunks
+			if !ok {
+				p.finished = true
+				break
+			}
+		}
+		frame, ok := <-p.current
+		if !ok {
+			p.current = nil
+			continue
+		}
+		if frame.Err != nil {
+			p.finished = true
+			p.Close()
+			return nil, frame.Err
+		}
+		return frame.Image, nil
+	}
+	return nil, io.EOF
+}
+
+// Close stops all decoding processes.
+func (p *ParallelVideoReader) Close() error {
+	p.cancel()
+	go func() {
+		// Unblock the scheduler if it is waiting to send.
+		for range p.chunks {
+		}
+	}()
+	p.wg.Wait()
+	return nil
+}
+
+// DecodeParallel decodes a video file using multiple
+// ffmpeg processes at once, calling f for every frame
+// along with the index of the frame in the video.
+//
+// Frames are not passed to f in order, and f is called
+// concurrently from multiple goroutines.
+//
+// If f returns an error, decoding is stopped and the error
+// is returned.
+func DecodeParallel(path string, opts *ParallelVideoReaderOptions,
+	f func(index int, frame image.Image) error) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "decode video in parallel")
+		}
+	}()
+
+	plan, err := planParallelDecode(path, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	chunks := make(chan *videoChunk, len(plan.Chunks))
+	for _, chunk := range plan.Chunks {
+		chunks <- chunk
+	}
+	close(chunks)
+
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	var firstErr error
+	for i := 0; i < plan.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				plan.Decode(ctx, chunk, func(frame chunkFrame) bool {
+					err := frame.Err
+					if err == nil {
+						err = f(frame.Index, frame.Image)
+					}
+					if err != nil {
+						errLock.Lock()
+						if firstErr == nil {
+							firstErr = err
+						}
+						errLock.Unlock()
+						cancel()
+						return false
+					}
+					return ctx.Err() == nil
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+type parallelDecodePlan struct {
+	Path        string
+	StreamIndex int
+	Info        *VideoInfo
+	Workers     int
+	Chunks      []*videoChunk
+}
+
+func planParallelDecode(path string, opts *ParallelVideoReaderOptions) (*parallelDecodePlan, error) {
+	stream, info, err := getVideoStream(path, opts.Stream)
+	if err != nil {
+		return nil, err
+	}
+	index, err := probeFrameIndex(path, stream.Index)
+	if err != nil {
+		return nil, err
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	chunkFrames := opts.ChunkFrames
+	if chunkFrames <= 0 {
+		chunkFrames = len(index.PTS) / (workers * 4)
+	}
+	return &parallelDecodePlan{
+		Path:        path,
+		StreamIndex: stream.Index,
+		Info:        info,
+		Workers:     workers,
+		Chunks:      index.Chunks(chunkFrames),
+	}, nil
+}
+
+// Decode decodes the frames of a chunk, passing each one to
+// f until f returns false.
+//
+// If an error occurs, it is passed to f as the final frame.
+func (p *parallelDecodePlan) Decode(ctx context.Context, chunk *videoChunk, f func(chunkFrame) bool) {
+	reader, err := startVideoReader(p.Info, chunk.Args(p.Path, p.StreamIndex), false)
+	if err != nil {
+		f(chunkFrame{Err: err})
+		return
+	}
+	defer reader.Close()
+	for i := 0; i < chunk.NumFrames; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+		img, err := reader.ReadFrame()
+		if err == io.EOF {
+			// Some frames may fail to decode, in which case
+			// the chunk will be shorter than expected.
+			return
+		} else if err != nil {
+			f(chunkFrame{Err: err})
+			return
+		}
+		if !f(chunkFrame{Index: chunk.FirstFrame + i, Image: img}) {
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package ffmpego
+
+import (
+	"image"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestParallelVideoReader(t *testing.T) {
+	reader, err := NewParallelVideoReader(filepath.Join("test_data", "test_video.mp4"),
+		&ParallelVideoReaderOptions{Workers: 2, ChunkFrames: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	expected, err := NewVideoReader(filepath.Join("test_data", "test_video.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer expected.Close()
+
+	numFrames := 0
+	for {
+		frame, err := reader.ReadFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		expectedFrame, err := expected.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !imagesEqual(frame, expectedFrame) {
+			t.Errorf("frame %d differs from sequential decode", numFrames)
+		}
+		numFrames++
+	}
+	if numFrames != 24 {
+		t.Errorf("incorrect number of frames: %d", numFrames)
+	}
+}
+
+func TestDecodeParallel(t *testing.T) {
+	var lock sync.Mutex
+	seen := map[int]bool{}
+	err := DecodeParallel(filepath.Join("test_data", "test_video.mp4"),
+		&ParallelVideoReaderOptions{Workers: 3, ChunkFrames: 1},
+		func(index int, frame image.Image) error {
+			lock.Lock()
+			defer lock.Unlock()
+			if seen[index] {
+				t.Errorf("duplicate frame index: %d", index)
+			}
+			seen[index] = true
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 24; i++ {
+		if !seen[i] {
+			t.Errorf("missing frame index: %d", i)
+		}
+	}
+}
+
+func TestDecodeParallelNegativeOptions(t *testing.T) {
+	var lock sync.Mutex
+	numFrames := 0
+	err := DecodeParallel(filepath.Join("test_data", "test_video.mp4"),
+		&ParallelVideoReaderOptions{Workers: -1, ChunkFrames: -1, BufferFrames: -1},
+		func(index int, frame image.Image) error {
+			lock.Lock()
+			defer lock.Unlock()
+			numFrames++
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numFrames != 24 {
+		t.Errorf("incorrect number of frames: %d", numFrames)
+	}
+}
+
+func imagesEqual(img1, img2 image.Image) bool {
+	if img1.Bounds() != img2.Bounds() {
+		return false
+	}
+	b := img1.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r1, g1, b1, a1 := img1.At(x, y).RGBA()
+			r2, g2, b2, a2 := img2.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return false
+			}
+		}
+	}
+	return true
+}
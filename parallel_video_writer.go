@@ -0,0 +1,317 @@
+package ffmpego
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ParallelVideoWriterOptions configures how a
+// ParallelVideoWriter splits up a video.
+type ParallelVideoWriterOptions struct {
+	// SegmentFrames is the number of frames to encode in
+	// each ffmpeg process.
+	//
+	// If 0 or negative, TotalFrames must be set, and the
+	// video is split evenly between the encoders, except
+	// that segments are limited to about 256MB of raw
+	// frames so that long videos do not fill up the disk.
+	SegmentFrames int
+
+	// TotalFrames is the number of frames that will be
+	// written, if known ahead of time.
+	TotalFrames int
+
+	// Encoders is the maximum number of ffmpeg processes to
+	// run at once. If 0 or negative, 4 is used.
+	Encoders int
+
+	// AudioFile, if specified, is a video or audio file to
+	// copy audio from, like NewVideoWriterWithAudio.
+	AudioFile string
+
+	// Encoding configures the video encoder for each
+	// segment. If nil, DefaultVideoEncoding() is used.
+	Encoding *VideoEncoding
+
+	// Metadata sets global tags of the output, such as
+	// "title" or "creation_time".
+	Metadata Metadata
+
+	// VideoMetadata sets tags of the video stream.
+	VideoMetadata Metadata
+
+	// Chapters, if non-empty, divides the output into named
+	// sections.
+	Chapters []*Chapter
+}
+
+// A ParallelVideoWriter encodes a video file using
+// multiple ffmpeg processes at once.
+//
+// The video is split into fixed-length segments which are
+// encoded separately with closed GOPs, and then joined
+// without re-encoding once the writer is closed.
+//
+// Raw frames are buffered in a temporary file for each
+// segment, so that later segments can be written while
+// earlier ones are being encoded. At most 2*Encoders
+// segments are buffered at once, after which WriteFrame
+// waits for an encoder to finish. Thus, the writer uses up
+// to 2*Encoders*SegmentFrames*width*height*3 bytes of
+// temporary disk space.
+type ParallelVideoWriter struct {
+	path   string
+	width  int
+	height int
+	fps    float64
+	opts   ParallelVideoWriterOptions
+
+	tempDir       string
+	segments      []string
+	current       *os.File
+	currentBuf    *bufio.Writer
+	currentFrames int
+
+	// runEncoder runs an ffmpeg command to encode one
+	// segment.
+	runEncoder func(args ...string) error
+
+	encoderSem chan struct{}
+	bufferSem  chan struct{}
+	wg         sync.WaitGroup
+	errLock    sync.Mutex
+	err        error
+}
+
+// maxSegmentBytes is the maximum size of the raw frames of
+// a segment when the segment length is chosen
+// automatically.
+const maxSegmentBytes = 256 << 20
+
+// NewParallelVideoWriter creates a ParallelVideoWriter
+// which is encoding to the given file.
+func NewParallelVideoWriter(path string, width, height int, fps float64,
+	opts *ParallelVideoWriterOptions) (*ParallelVideoWriter, error) {
+	o := *opts
+	if o.Encoders <= 0 {
+		o.Encoders = 4
+	}
+	if o.SegmentFrames <= 0 {
+		if o.TotalFrames <= 0 {
+			return nil, errors.New("write video in parallel: either SegmentFrames or " +
+				"TotalFrames must be positive")
+		}
+		o.SegmentFrames = (o.TotalFrames + o.Encoders - 1) / o.Encoders
+		maxFrames := maxSegmentBytes / (3 * width * height)
+		if maxFrames < 1 {
+			maxFrames = 1
+		}
+		if o.SegmentFrames > maxFrames {
+			o.SegmentFrames = maxFrames
+		}
+	}
+	tempDir, err := ioutil.TempDir("", "ffmpego-parallel")
+	if err != nil {
+		return nil, errors.Wrap(err, "write video in parallel")
+	}
+	return &ParallelVideoWriter{
+		path:    path,
+		width:   width,
+		height:  height,
+		fps:     fps,
+		opts:    o,
+		tempDir: tempDir,
+		runEncoder: func(args ...string) error {
+			_, err := runFFmpeg(context.Background(), args...)
+			return err
+		},
+		encoderSem: make(chan struct{}, o.Encoders),
+		bufferSem:  make(chan struct{}, 2*o.Encoders),
+	}, nil
+}
+
+// WriteFrame adds a frame to the current video.
+func (p *ParallelVideoWriter) WriteFrame(img image.Image) error {
+	if err := p.firstError(); err != nil {
+		return errors.Wrap(err, "write frame")
+	}
+	if p.current == nil {
+		if err := p.startSegment(); err != nil {
+			return errors.Wrap(err, "write frame")
+		}
+	}
+	data, err := rgbFrameData(img, p.width, p.height)
+	if err != nil {
+		return errors.Wrap(err, "write frame")
+	}
+	if _, err := p.currentBuf.Write(data); err != nil {
+		return errors.Wrap(err, "write frame")
+	}
+	p.currentFrames++
+	if p.currentFrames == p.opts.SegmentFrames {
+		if err := p.finishSegment(); err != nil {
+			return errors.Wrap(err, "write frame")
+		}
+	}
+	return nil
+}
+
+// Close finishes encoding all of the segments and joins
+// them into the output file.
+func (p *ParallelVideoWriter) Close() (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "close parallel video writer")
+		}
+	}()
+	defer os.RemoveAll(p.tempDir)
+
+	if p.current != nil {
+		if err := p.finishSegment(); err != nil {
+			p.wg.Wait()
+			return err
+		}
+	}
+	p.wg.Wait()
+	if err := p.firstError(); err != nil {
+		return err
+	}
+	if len(p.segments) == 0 {
+		return errors.New("no frames were written")
+	}
+
+	listPath := filepath.Join(p.tempDir, "segments.txt")
+	if err := writeConcatList(listPath, p.segments); err != nil {
+		return err
+	}
+	args := []string{"-y", "-f", "concat", "-safe", "0", "-i", listPath}
+	if p.opts.AudioFile != "" {
+		args = append(args, "-i", p.opts.AudioFile)
+	}
+	var chapterFlags []string
+	if len(p.opts.Chapters) > 0 {
+		chapterFile, err := writeChapterFile(p.opts.Chapters)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(chapterFile)
+		inputIndex := 1
+		if p.opts.AudioFile != "" {
+			inputIndex = 2
+		}
+		var chapterInput []string
+		chapterInput, chapterFlags = chapterInputArgs(chapterFile, inputIndex)
+		args = append(args, chapterInput...)
+	}
+	if p.opts.AudioFile != "" {
+		args = append(args, "-map", "0:v:0", "-map", "1:a:0?")
+	}
+	args = append(args, chapterFlags...)
+	args = append(args, "-c", "copy")
+	args = append(args, outputMetadataFlags(p.opts.Metadata, p.opts.VideoMetadata, nil)...)
+	args = append(args, p.path)
+	_, err = runFFmpeg(context.Background(), args...)
+	return err
+}
+
+func (p *ParallelVideoWriter) startSegment() error {
+	p.bufferSem <- struct{}{}
+	rawPath := filepath.Join(p.tempDir, fmt.Sprintf("segment_%06d.rgb", len(p.segments)))
+	f, err := os.Create(rawPath)
+	if err != nil {
+		<-p.bufferSem
+		return err
+	}
+	p.segments = append(p.segments, strings.TrimSuffix(rawPath, ".rgb")+".mp4")
+	p.current = f
+	p.currentBuf = bufio.NewWriter(f)
+	p.currentFrames = 0
+	return nil
+}
+
+// finishSegment closes the raw frames of the current
+// segment and starts encoding them in the background once
+// an encoder is available.
+func (p *ParallelVideoWriter) finishSegment() error {
+	f := p.current
+	p.current = nil
+	err := p.currentBuf.Flush()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		<-p.bufferSem
+		return err
+	}
+	segPath := p.segments[len(p.segments)-1]
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() {
+			<-p.bufferSem
+		}()
+		p.encoderSem <- struct{}{}
+		err := p.encodeSegment(f.Name(), segPath)
+		<-p.encoderSem
+		os.Remove(f.Name())
+		if err != nil {
+			p.errLock.Lock()
+			if p.err == nil {
+				p.err = err
+			}
+			p.errLock.Unlock()
+		}
+	}()
+	return nil
+}
+
+func (p *ParallelVideoWriter) encodeSegment(rawPath, segPath string) error {
+	encoding := p.opts.Encoding
+	if encoding == nil {
+		encoding = DefaultVideoEncoding()
+	}
+	segEncoding := *encoding
+	segEncoding.ExtraFlags = append(
+		append([]string{}, encoding.ExtraFlags...),
+		// Closed GOPs guarantee that segments can be joined
+		// without decoding any frames.
+		"-flags", "+cgop",
+	)
+	args := append([]string{"-y"}, rawVideoInputArgs(rawPath, p.width, p.height, p.fps)...)
+	args = append(args, videoEncodingFlags(&segEncoding)...)
+	return p.runEncoder(append(args, segPath)...)
+}
+
+func (p *ParallelVideoWriter) firstError() error {
+	p.errLock.Lock()
+	defer p.errLock.Unlock()
+	return p.err
+}
+
+// writeConcatList creates a file listing for ffmpeg's
+// concat demuxer.
+func writeConcatList(path string, files []string) error {
+	var lines []string
+	lines = append(lines, "ffconcat version 1.0")
+	for _, file := range files {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, "file "+quoteConcatPath(absPath))
+	}
+	return ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+func quoteConcatPath(path string) string {
+	return "'" + strings.ReplaceAll(path, "'", "'\\''") + "'"
+}
@@ -0,0 +1,124 @@
+package ffmpego
+
+import (
+	"image"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParallelVideoWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-parallel-video-writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	outPath := filepath.Join(dir, "out.mp4")
+	vw, err := NewParallelVideoWriter(outPath, 50, 50, 12, &ParallelVideoWriterOptions{
+		SegmentFrames: 7,
+		Encoders:      2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 30; i++ {
+		frame := image.NewGray(image.Rect(0, 0, 50, 50))
+		for j := 0; j < (len(frame.Pix)*i)/30; j++ {
+			frame.Pix[j] = 0xff
+		}
+		if err := vw.WriteFrame(frame); err != nil {
+			vw.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := vw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewVideoReader(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	numFrames := 0
+	for {
+		if _, err := reader.ReadFrame(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		numFrames++
+	}
+	if numFrames != 30 {
+		t.Errorf("expected 30 frames but got %d", numFrames)
+	}
+}
+
+func TestParallelVideoWriterConcurrency(t *testing.T) {
+	vw, err := NewParallelVideoWriter("out.mp4", 8, 8, 12, &ParallelVideoWriterOptions{
+		SegmentFrames: 2,
+		Encoders:      3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(vw.tempDir)
+
+	var lock sync.Mutex
+	var running, maxRunning, numEncoded int
+	vw.runEncoder = func(args ...string) error {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		lock.Lock()
+		running--
+		numEncoded++
+		lock.Unlock()
+		return nil
+	}
+
+	for i := 0; i < 20; i++ {
+		if err := vw.WriteFrame(image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vw.wg.Wait()
+	if numEncoded != 10 {
+		t.Errorf("expected 10 segments to be encoded but got %d", numEncoded)
+	}
+	if maxRunning < 2 || maxRunning > 3 {
+		t.Errorf("expected 2 or 3 concurrent encoders but got %d", maxRunning)
+	}
+}
+
+func TestParallelVideoWriterBadOptions(t *testing.T) {
+	_, err := NewParallelVideoWriter("out.mp4", 8, 8, 12, &ParallelVideoWriterOptions{})
+	if err == nil {
+		t.Error("expected an error without SegmentFrames or TotalFrames")
+	}
+}
+
+func TestParallelVideoWriterSegmentLimit(t *testing.T) {
+	vw, err := NewParallelVideoWriter("out.mp4", 1920, 1080, 24, &ParallelVideoWriterOptions{
+		TotalFrames: 24 * 60 * 60 * 2,
+		Encoders:    4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(vw.tempDir)
+	if bytes := vw.opts.SegmentFrames * 1920 * 1080 * 3; bytes > maxSegmentBytes {
+		t.Errorf("segments are too large: %d bytes", bytes)
+	}
+	if vw.opts.SegmentFrames < 1 {
+		t.Errorf("invalid segment frames: %d", vw.opts.SegmentFrames)
+	}
+}
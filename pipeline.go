@@ -0,0 +1,172 @@
+package ffmpego
+
+import (
+	"context"
+	"image"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// A FrameReader is a source of video frames, such as a
+// VideoReader.
+type FrameReader interface {
+	// ReadFrame reads the next frame, returning io.EOF
+	// once there are no more frames.
+	ReadFrame() (image.Image, error)
+}
+
+// A FrameWriter is a destination for video frames, such as
+// a VideoWriter.
+type FrameWriter interface {
+	WriteFrame(img image.Image) error
+}
+
+// A Pipeline processes frames from a FrameReader on
+// multiple goroutines and writes the results, in order, to
+// a FrameWriter.
+//
+// Decoding, processing, and encoding all happen at the same
+// time, so ffmpeg and Go code can run in parallel.
+type Pipeline struct {
+	// Process is called on every frame to produce the
+	// frame that should be written.
+	//
+	// It is called concurrently from multiple goroutines.
+	Process func(img image.Image) image.Image
+
+	// Workers is the number of goroutines to run Process
+	// on. If 0 or negative, runtime.GOMAXPROCS(0) is used.
+	Workers int
+
+	// Prefetch is the number of frames which may be read
+	// ahead of the frame currently being written, in
+	// addition to the frames being processed.
+	// If 0 or negative, 2*Workers is used.
+	Prefetch int
+}
+
+// Run reads every frame from r, processes it, and writes
+// it to w.
+//
+// Run returns once all frames have been written, once an
+// error is encountered, or once ctx is cancelled. The
+// reader and writer are not closed.
+func (p *Pipeline) Run(ctx context.Context, r FrameReader, w FrameWriter) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "run pipeline")
+		}
+	}()
+
+	numWorkers := p.Workers
+	if numWorkers <= 0 {
+		numWorkers = runtime.GOMAXPROCS(0)
+	}
+	prefetch := p.Prefetch
+	if prefetch <= 0 {
+		prefetch = 2 * numWorkers
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type indexedFrame struct {
+		Index int
+		Image image.Image
+	}
+
+	// Every frame holds a token from the time it is read
+	// until it is written, bounding memory usage.
+	tokens := make(chan struct{}, numWorkers+prefetch)
+	inputs := make(chan indexedFrame, prefetch)
+	outputs := make(chan indexedFrame, numWorkers+prefetch)
+	readErr := make(chan error, 1)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(inputs)
+		for i := 0; true; i++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			frame, err := r.ReadFrame()
+			if err != nil {
+				if err != io.EOF {
+					readErr <- err
+				}
+				return
+			}
+			select {
+			case inputs <- indexedFrame{Index: i, Image: frame}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var workerWg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		workerWg.Add(1)
+		go func() {
+			defer workerWg.Done()
+			for frame := range inputs {
+				if ctx.Err() != nil {
+					return
+				}
+				frame.Image = p.Process(frame.Image)
+				outputs <- frame
+			}
+		}()
+	}
+	go func() {
+		workerWg.Wait()
+		close(outputs)
+	}()
+
+	// Make sure no goroutines are running once we return,
+	// so the caller may safely close the reader and writer.
+	defer func() {
+		cancel()
+		for range outputs {
+		}
+		wg.Wait()
+	}()
+
+	pending := map[int]image.Image{}
+	nextIndex := 0
+	for {
+		select {
+		case frame, ok := <-outputs:
+			if !ok {
+				select {
+				case err := <-readErr:
+					return err
+				default:
+				}
+				return ctx.Err()
+			}
+			pending[frame.Index] = frame.Image
+			for {
+				img, ok := pending[nextIndex]
+				if !ok {
+					break
+				}
+				delete(pending, nextIndex)
+				nextIndex++
+				if err := w.WriteFrame(img); err != nil {
+					return err
+				}
+				<-tokens
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
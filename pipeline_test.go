@@ -0,0 +1,128 @@
+package ffmpego
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPipeline(t *testing.T) {
+	reader := &testFrameReader{NumFrames: 100}
+	writer := &testFrameWriter{}
+	pipeline := &Pipeline{
+		Workers: 4,
+		Process: func(img image.Image) image.Image {
+			time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+			g := img.(*image.Gray)
+			return &image.Gray{
+				Pix:    []uint8{g.Pix[0] + 1},
+				Stride: 1,
+				Rect:   g.Rect,
+			}
+		},
+	}
+	if err := pipeline.Run(context.Background(), reader, writer); err != nil {
+		t.Fatal(err)
+	}
+	if len(writer.Frames) != 100 {
+		t.Fatalf("expected 100 frames but got %d", len(writer.Frames))
+	}
+	for i, frame := range writer.Frames {
+		if actual := frame.(*image.Gray).GrayAt(0, 0).Y; actual != uint8(i+1) {
+			t.Errorf("frame %d: expected value %d but got %d", i, i+1, actual)
+		}
+	}
+}
+
+func TestPipelineNegativeOptions(t *testing.T) {
+	reader := &testFrameReader{NumFrames: 10}
+	writer := &testFrameWriter{}
+	pipeline := &Pipeline{
+		Workers:  -1,
+		Prefetch: -5,
+		Process: func(img image.Image) image.Image {
+			return img
+		},
+	}
+	if err := pipeline.Run(context.Background(), reader, writer); err != nil {
+		t.Fatal(err)
+	}
+	if len(writer.Frames) != 10 {
+		t.Errorf("expected 10 frames but got %d", len(writer.Frames))
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	identity := func(img image.Image) image.Image {
+		return img
+	}
+
+	readErr := errors.New("read failure")
+	reader := &testFrameReader{NumFrames: 100, FailAt: 50, Err: readErr}
+	writer := &testFrameWriter{}
+	err := (&Pipeline{Process: identity}).Run(context.Background(), reader, writer)
+	if err == nil || !errors.Is(err, readErr) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(writer.Frames) != 50 {
+		t.Errorf("expected 50 frames but got %d", len(writer.Frames))
+	}
+
+	writeErr := errors.New("write failure")
+	reader = &testFrameReader{NumFrames: 100}
+	writer = &testFrameWriter{FailAt: 10, Err: writeErr}
+	err = (&Pipeline{Process: identity}).Run(context.Background(), reader, writer)
+	if err == nil || !errors.Is(err, writeErr) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reader = &testFrameReader{NumFrames: 100}
+	writer = &testFrameWriter{}
+	err = (&Pipeline{Process: identity}).Run(ctx, reader, writer)
+	if err == nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+type testFrameReader struct {
+	NumFrames int
+	FailAt    int
+	Err       error
+
+	index int
+}
+
+func (t *testFrameReader) ReadFrame() (image.Image, error) {
+	if t.Err != nil && t.index == t.FailAt {
+		return nil, t.Err
+	}
+	if t.index == t.NumFrames {
+		return nil, io.EOF
+	}
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+	img.SetGray(0, 0, color.Gray{Y: uint8(t.index)})
+	t.index++
+	return img, nil
+}
+
+type testFrameWriter struct {
+	FailAt int
+	Err    error
+
+	Frames []image.Image
+}
+
+func (t *testFrameWriter) WriteFrame(img image.Image) error {
+	if t.Err != nil && len(t.Frames) == t.FailAt {
+		return t.Err
+	}
+	t.Frames = append(t.Frames, img)
+	return nil
+}
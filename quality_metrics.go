@@ -0,0 +1,344 @@
+package ffmpego
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A Metric is an objective measure of video quality.
+type Metric string
+
+const (
+	MetricPSNR Metric = "psnr"
+	MetricSSIM Metric = "ssim"
+	MetricVMAF Metric = "vmaf"
+)
+
+// filterName gets the ffmpeg filter which computes the
+// metric.
+func (m Metric) filterName() string {
+	if m == MetricVMAF {
+		return "libvmaf"
+	}
+	return string(m)
+}
+
+// MetricScores stores the results of a quality metric.
+type MetricScores struct {
+	// Aggregate is the overall score reported by ffmpeg.
+	//
+	// For PSNR, this is computed from the average error
+	// across all frames, so it may differ from the mean of
+	// Frames.
+	Aggregate float64
+
+	// Frames contains the score of every frame.
+	Frames []float64
+}
+
+// CompareVideos computes quality metrics for a distorted
+// video, such as an encode, against a reference video.
+//
+// If no metrics are specified, PSNR and SSIM are used.
+// VMAF is only available if ffmpeg was built with libvmaf,
+// which can be checked with HasFilter("libvmaf").
+//
+// If the videos differ in size, the distorted video is
+// scaled to the size of the reference.
+func CompareVideos(reference, distorted string, metrics ...Metric) (scores map[Metric]*MetricScores,
+	err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "compare videos")
+		}
+	}()
+
+	if len(metrics) == 0 {
+		metrics = []Metric{MetricPSNR, MetricSSIM}
+	}
+	for _, metric := range metrics {
+		if ok, err := HasFilter(metric.filterName()); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, errors.Errorf("ffmpeg does not support the %s filter", metric.filterName())
+		}
+	}
+
+	refStream, refInfo, err := getVideoStream(reference, nil)
+	if err != nil {
+		return nil, err
+	}
+	distStream, _, err := getVideoStream(distorted, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tempDir, err := ioutil.TempDir("", "ffmpego-compare")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	n := len(metrics)
+	graph := []string{
+		fmt.Sprintf("[0:%d]scale=%d:%d,setpts=PTS-STARTPTS,split=%d%s", distStream.Index,
+			refInfo.Width, refInfo.Height, n, filterLabels("d", n)),
+		fmt.Sprintf("[1:%d]setpts=PTS-STARTPTS,split=%d%s", refStream.Index, n, filterLabels("r", n)),
+	}
+	logPaths := make([]string, n)
+	for i, metric := range metrics {
+		logPaths[i] = filepath.Join(tempDir, string(metric)+".log")
+		logPath := escapeFilterValue(logPaths[i])
+		var filter string
+		switch metric {
+		case MetricPSNR, MetricSSIM:
+			filter = string(metric) + "=stats_file=" + logPath
+		case MetricVMAF:
+			filter = "libvmaf=log_fmt=json:log_path=" + logPath
+		default:
+			return nil, errors.Errorf("unknown metric: %s", metric)
+		}
+		graph = append(graph, fmt.Sprintf("[d%d][r%d]%s", i, i, filter))
+	}
+
+	lines, err := runFFmpeg(
+		context.Background(),
+		"-i", distorted, "-i", reference,
+		"-filter_complex", strings.Join(graph, ";"),
+		"-f", "null", "-",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	scores = map[Metric]*MetricScores{}
+	for i, metric := range metrics {
+		logData, err := ioutil.ReadFile(logPaths[i])
+		if err != nil {
+			return nil, err
+		}
+		var result *MetricScores
+		if metric == MetricVMAF {
+			result, err = parseVMAFLog(logData)
+		} else {
+			result, err = parseMetricLog(metric, lines, logData)
+		}
+		if err != nil {
+			return nil, err
+		}
+		scores[metric] = result
+	}
+	return scores, nil
+}
+
+func filterLabels(prefix string, n int) string {
+	var labels string
+	for i := 0; i < n; i++ {
+		labels += fmt.Sprintf("[%s%d]", prefix, i)
+	}
+	return labels
+}
+
+// parseMetricLog parses the aggregate PSNR or SSIM from
+// ffmpeg's log, and per-frame scores from a stats file.
+func parseMetricLog(metric Metric, lines []string, stats []byte) (*MetricScores, error) {
+	var aggregateExp, frameExp *regexp.Regexp
+	if metric == MetricPSNR {
+		aggregateExp = regexp.MustCompilePOSIX("PSNR .*average:([^ ]+)")
+		frameExp = regexp.MustCompilePOSIX("psnr_avg:([^ ]+)")
+	} else {
+		aggregateExp = regexp.MustCompilePOSIX("SSIM .*All:([^ ]+)")
+		frameExp = regexp.MustCompilePOSIX("All:([^ ]+)")
+	}
+
+	result := &MetricScores{}
+	var foundAggregate bool
+	for _, line := range lines {
+		if match := aggregateExp.FindStringSubmatch(line); match != nil {
+			score, err := strconv.ParseFloat(match[1], 64)
+			if err != nil {
+				return nil, errors.Wrap(err, "parse "+string(metric))
+			}
+			result.Aggregate = score
+			foundAggregate = true
+		}
+	}
+	if !foundAggregate {
+		return nil, errors.Errorf("could not find %s in output", metric)
+	}
+	for _, line := range strings.Split(string(stats), "\n") {
+		if match := frameExp.FindStringSubmatch(line); match != nil {
+			score, err := strconv.ParseFloat(match[1], 64)
+			if err != nil {
+				return nil, errors.Wrap(err, "parse "+string(metric))
+			}
+			result.Frames = append(result.Frames, score)
+		}
+	}
+	return result, nil
+}
+
+// parseVMAFLog parses the JSON log written by libvmaf,
+// supporting both the libvmaf 1.x and 2.x formats.
+func parseVMAFLog(data []byte) (*MetricScores, error) {
+	var log struct {
+		Frames []struct {
+			Metrics struct {
+				VMAF *float64 `json:"vmaf"`
+			} `json:"metrics"`
+			VMAFScore *float64 `json:"VMAF_score"`
+		} `json:"frames"`
+		PooledMetrics struct {
+			VMAF struct {
+				Mean *float64 `json:"mean"`
+			} `json:"vmaf"`
+		} `json:"pooled_metrics"`
+		VMAFScore *float64 `json:"VMAF score"`
+	}
+	if err := json.Unmarshal(data, &log); err != nil {
+		return nil, errors.Wrap(err, "parse vmaf log")
+	}
+	result := &MetricScores{}
+	if log.PooledMetrics.VMAF.Mean != nil {
+		result.Aggregate = *log.PooledMetrics.VMAF.Mean
+	} else if log.VMAFScore != nil {
+		result.Aggregate = *log.VMAFScore
+	} else {
+		return nil, errors.New("could not find vmaf in log")
+	}
+	for _, frame := range log.Frames {
+		if frame.Metrics.VMAF != nil {
+			result.Frames = append(result.Frames, *frame.Metrics.VMAF)
+		} else if frame.VMAFScore != nil {
+			result.Frames = append(result.Frames, *frame.VMAFScore)
+		}
+	}
+	return result, nil
+}
+
+// PSNR computes the peak signal-to-noise ratio between two
+// equally sized images, in decibels, using the error of
+// their 8-bit RGB channels.
+//
+// For identical images, the result is positive infinity.
+//
+// An error is returned if the images are empty or have
+// different sizes.
+func PSNR(img1, img2 image.Image) (float64, error) {
+	if err := checkMetricBounds(img1, img2); err != nil {
+		return 0, errors.Wrap(err, "compute PSNR")
+	}
+	b1, b2 := img1.Bounds(), img2.Bounds()
+	var sum float64
+	for y := 0; y < b1.Dy(); y++ {
+		for x := 0; x < b1.Dx(); x++ {
+			r1, g1, bl1, _ := img1.At(b1.Min.X+x, b1.Min.Y+y).RGBA()
+			r2, g2, bl2, _ := img2.At(b2.Min.X+x, b2.Min.Y+y).RGBA()
+			for _, diff := range []float64{
+				float64(r1>>8) - float64(r2>>8),
+				float64(g1>>8) - float64(g2>>8),
+				float64(bl1>>8) - float64(bl2>>8),
+			} {
+				sum += diff * diff
+			}
+		}
+	}
+	mse := sum / float64(3*b1.Dx()*b1.Dy())
+	if mse == 0 {
+		return math.Inf(1), nil
+	}
+	return 10 * math.Log10(255*255/mse), nil
+}
+
+// SSIM computes the structural similarity between the
+// luma of two equally sized images.
+//
+// Like ffmpeg's ssim filter, this averages the SSIM of 8x8
+// windows spaced 4 pixels apart. Images smaller than 8x8
+// are compared with a single window covering the image.
+//
+// An error is returned if the images are empty or have
+// different sizes.
+func SSIM(img1, img2 image.Image) (float64, error) {
+	if err := checkMetricBounds(img1, img2); err != nil {
+		return 0, errors.Wrap(err, "compute SSIM")
+	}
+	luma1, luma2 := imageLuma(img1), imageLuma(img2)
+	width, height := img1.Bounds().Dx(), img1.Bounds().Dy()
+
+	windowWidth, windowHeight := 8, 8
+	if width < windowWidth {
+		windowWidth = width
+	}
+	if height < windowHeight {
+		windowHeight = height
+	}
+
+	const c1 = (0.01 * 255) * (0.01 * 255)
+	const c2 = (0.03 * 255) * (0.03 * 255)
+	var total float64
+	var count int
+	for y := 0; y+windowHeight <= height; y += 4 {
+		for x := 0; x+windowWidth <= width; x += 4 {
+			var sum1, sum2, sumSq1, sumSq2, sumProd float64
+			for wy := y; wy < y+windowHeight; wy++ {
+				for wx := x; wx < x+windowWidth; wx++ {
+					p1, p2 := luma1[wy*width+wx], luma2[wy*width+wx]
+					sum1 += p1
+					sum2 += p2
+					sumSq1 += p1 * p1
+					sumSq2 += p2 * p2
+					sumProd += p1 * p2
+				}
+			}
+			n := float64(windowWidth * windowHeight)
+			mean1, mean2 := sum1/n, sum2/n
+			var1 := sumSq1/n - mean1*mean1
+			var2 := sumSq2/n - mean2*mean2
+			covar := sumProd/n - mean1*mean2
+			total += ((2*mean1*mean2 + c1) * (2*covar + c2)) /
+				((mean1*mean1 + mean2*mean2 + c1) * (var1 + var2 + c2))
+			count++
+		}
+	}
+	return total / float64(count), nil
+}
+
+// checkMetricBounds checks that two images can be compared
+// by PSNR or SSIM.
+func checkMetricBounds(img1, img2 image.Image) error {
+	b1, b2 := img1.Bounds(), img2.Bounds()
+	if b1.Empty() || b2.Empty() {
+		return errors.New("images must not be empty")
+	}
+	if b1.Dx() != b2.Dx() || b1.Dy() != b2.Dy() {
+		return errors.Errorf("image sizes do not match: %dx%d and %dx%d",
+			b1.Dx(), b1.Dy(), b2.Dx(), b2.Dy())
+	}
+	return nil
+}
+
+// imageLuma computes the BT.601 luma of every pixel in an
+// image, in the range [0, 255].
+func imageLuma(img image.Image) []float64 {
+	b := img.Bounds()
+	result := make([]float64, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			result = append(result, (0.299*float64(r)+0.587*float64(g)+0.114*float64(bl))/0x101)
+		}
+	}
+	return result
+}
@@ -0,0 +1,121 @@
+package ffmpego
+
+import (
+	"image"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareVideos(t *testing.T) {
+	path := filepath.Join("test_data", "test_video.mp4")
+	scores, err := CompareVideos(path, path, MetricPSNR, MetricSSIM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssim := scores[MetricSSIM]
+	if ssim == nil || math.Abs(ssim.Aggregate-1) > 1e-3 {
+		t.Errorf("unexpected SSIM: %v", ssim)
+	} else if len(ssim.Frames) != 24 {
+		t.Errorf("expected 24 frames but got %d", len(ssim.Frames))
+	}
+	if psnr := scores[MetricPSNR]; psnr == nil || len(psnr.Frames) != 24 {
+		t.Errorf("unexpected PSNR: %v", psnr)
+	}
+}
+
+func TestParseMetricLog(t *testing.T) {
+	log := `[Parsed_psnr_4 @ 0x5560] PSNR y:43.21 u:46.00 v:46.10 average:44.10 min:40.20 max:inf
+[Parsed_ssim_5 @ 0x5561] SSIM Y:0.990 (20.0) U:0.995 (23.0) V:0.995 (23.0) All:0.992 (21.0)`
+	lines := strings.Split(log, "\n")
+
+	psnrStats := "n:1 mse_avg:2.00 mse_y:2.50 psnr_avg:45.12 psnr_y:44.15\n" +
+		"n:2 mse_avg:0.00 mse_y:0.00 psnr_avg:inf psnr_y:inf\n"
+	scores, err := parseMetricLog(MetricPSNR, lines, []byte(psnrStats))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores.Aggregate != 44.10 || len(scores.Frames) != 2 || scores.Frames[0] != 45.12 ||
+		!math.IsInf(scores.Frames[1], 1) {
+		t.Errorf("unexpected PSNR scores: %v", scores)
+	}
+
+	ssimStats := "n:1 Y:0.99 U:0.98 V:0.98 All:0.985 (18.2)\n"
+	scores, err = parseMetricLog(MetricSSIM, lines, []byte(ssimStats))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores.Aggregate != 0.992 || len(scores.Frames) != 1 || scores.Frames[0] != 0.985 {
+		t.Errorf("unexpected SSIM scores: %v", scores)
+	}
+}
+
+func TestParseVMAFLog(t *testing.T) {
+	v2 := `{"frames": [{"frameNum": 0, "metrics": {"vmaf": 95.5}},
+		{"frameNum": 1, "metrics": {"vmaf": 96.5}}],
+		"pooled_metrics": {"vmaf": {"min": 95.5, "max": 96.5, "mean": 96.0}}}`
+	v1 := `{"frames": [{"frameNum": 0, "VMAF_score": 90}], "VMAF score": 90}`
+	for i, data := range []string{v2, v1} {
+		scores, err := parseVMAFLog([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []float64{96, 90}[i]
+		if scores.Aggregate != expected || len(scores.Frames) != 2-i {
+			t.Errorf("log %d: unexpected scores: %v", i, scores)
+		}
+	}
+}
+
+func TestPSNRAndSSIM(t *testing.T) {
+	img1 := image.NewGray(image.Rect(0, 0, 16, 16))
+	img2 := image.NewGray(image.Rect(0, 0, 16, 16))
+	for i := range img1.Pix {
+		img1.Pix[i] = uint8(i % 200)
+		img2.Pix[i] = uint8(i % 200)
+	}
+	if psnr, err := PSNR(img1, img2); err != nil || !math.IsInf(psnr, 1) {
+		t.Errorf("PSNR of identical images should be infinite, got %f (%v)", psnr, err)
+	}
+	if ssim, err := SSIM(img1, img2); err != nil || math.Abs(ssim-1) > 1e-8 {
+		t.Errorf("SSIM of identical images should be 1, got %f (%v)", ssim, err)
+	}
+
+	for i := range img2.Pix {
+		img2.Pix[i] += 1
+	}
+	if psnr, err := PSNR(img1, img2); err != nil || math.Abs(psnr-20*math.Log10(255)) > 1e-8 {
+		t.Errorf("unexpected PSNR: %f (%v)", psnr, err)
+	}
+	if ssim, err := SSIM(img1, img2); err != nil || ssim >= 1 || ssim < 0.9 {
+		t.Errorf("unexpected SSIM: %f (%v)", ssim, err)
+	}
+}
+
+func TestPSNRAndSSIMBadBounds(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	for _, other := range []image.Image{
+		image.NewGray(image.Rect(0, 0, 16, 8)),
+		image.NewGray(image.Rect(0, 0, 0, 0)),
+	} {
+		if _, err := PSNR(img, other); err == nil {
+			t.Errorf("expected PSNR error for bounds %v", other.Bounds())
+		}
+		if _, err := SSIM(img, other); err == nil {
+			t.Errorf("expected SSIM error for bounds %v", other.Bounds())
+		}
+	}
+	empty := image.NewGray(image.Rect(0, 0, 0, 0))
+	if _, err := SSIM(empty, empty); err == nil {
+		t.Error("expected SSIM error for empty images")
+	}
+}
+
+func TestEscapeFilterValue(t *testing.T) {
+	actual := escapeFilterValue(`C:\out's,file.log`)
+	expected := `C\\:\\\\out\\\'s\,file.log`
+	if actual != expected {
+		t.Errorf("expected %s but got %s", expected, actual)
+	}
+}
@@ -0,0 +1,52 @@
+package ffmpego
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Recover remuxes a file which was never finalized, such as
+// the output of a writer whose process was killed, into a
+// playable file without re-encoding it.
+//
+// Streams are copied up to the point where the input was
+// cut off, discarding any corrupt data at the end.
+// Subtitles are handled as in Remux.
+//
+// Fragmented MP4 and Matroska files can be recovered.
+// Regular MP4 files store their index at the end, so they
+// cannot be recovered if they were not closed; see the
+// Fragmented field of VideoWriterOptions.
+func Recover(ctx context.Context, input, output string) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "recover")
+		}
+	}()
+	info, err := getMediaInfo(input)
+	if err != nil {
+		return err
+	}
+	inputFlags := []string{"-err_detect", "ignore_err", "-fflags", "+discardcorrupt+genpts"}
+	_, err = runFFmpeg(ctx, remuxArgs(info, inputFlags, input, output)...)
+	return err
+}
+
+// fragmentedFlags gets output flags which make a file
+// readable even if it is never finalized.
+//
+// Formats other than MP4 and MOV, such as Matroska and
+// MPEG-TS, can already be read after being cut off.
+func fragmentedFlags(path string) []string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp4", ".m4v", ".m4a", ".mov":
+		// Each keyframe starts a fragment with its own index,
+		// rather than storing one index when the file is
+		// closed.
+		return []string{"-movflags", "frag_keyframe+empty_moov+default_base_moof"}
+	}
+	return nil
+}
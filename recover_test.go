@@ -0,0 +1,86 @@
+package ffmpego
+
+import (
+	"context"
+	"image"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFragmentedFlags(t *testing.T) {
+	expected := []string{"-movflags", "frag_keyframe+empty_moov+default_base_moof"}
+	if flags := fragmentedFlags("out.MP4"); !reflect.DeepEqual(flags, expected) {
+		t.Errorf("unexpected flags: %v", flags)
+	}
+	if flags := fragmentedFlags("out.mkv"); flags != nil {
+		t.Errorf("unexpected flags: %v", flags)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-recover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	partialPath := filepath.Join(dir, "partial.mp4")
+	encoding := DefaultVideoEncoding()
+	encoding.KeyframeInterval = 12
+	vw, err := NewVideoWriterWithOptions(partialPath, 64, 64, 12, &VideoWriterOptions{
+		Encoding:   encoding,
+		Fragmented: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 48; i++ {
+		frame := image.NewGray(image.Rect(0, 0, 64, 64))
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(i*5 + j)
+		}
+		if err := vw.WriteFrame(frame); err != nil {
+			vw.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := vw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a writer which was killed partway through.
+	data, err := ioutil.ReadFile(partialPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(partialPath, data[:len(data)*2/3], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := filepath.Join(dir, "recovered.mp4")
+	if err := Recover(context.Background(), partialPath, outPath); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := NewVideoReader(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	numFrames := 0
+	for {
+		_, err := reader.ReadFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		numFrames++
+	}
+	if numFrames < 12 || numFrames >= 48 {
+		t.Errorf("unexpected number of recovered frames: %d", numFrames)
+	}
+}
@@ -0,0 +1,129 @@
+package ffmpego
+
+import (
+	"image"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// A ReverseVideoReader decodes a video file from the last
+// frame to the first.
+//
+// The video is decoded one keyframe-aligned chunk at a
+// time, starting from the end of the file, so only one
+// group of pictures is stored in memory at once.
+//
+// Groups of pictures which would take more than 256MB of
+// decoded frames are split into smaller ranges. Each range
+// is decoded starting from its keyframe, so long groups of
+// pictures are decoded several times to bound the memory
+// usage.
+type ReverseVideoReader struct {
+	path        string
+	streamIndex int
+	info        *VideoInfo
+	chunks      []*videoChunk
+
+	frames []image.Image
+}
+
+// maxReverseBytes is the maximum amount of memory used by
+// the decoded frames of a ReverseVideoReader.
+const maxReverseBytes = 256 << 20
+
+// NewReverseVideoReader creates a ReverseVideoReader for
+// the first video stream in a file.
+func NewReverseVideoReader(path string) (*ReverseVideoReader, error) {
+	stream, info, err := getVideoStream(path, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "read video in reverse")
+	}
+	index, err := probeFrameIndex(path, stream.Index)
+	if err != nil {
+		return nil, errors.Wrap(err, "read video in reverse")
+	}
+	maxFrames := maxReverseBytes / (4 * info.Width * info.Height)
+	if maxFrames < 1 {
+		maxFrames = 1
+	}
+	return &ReverseVideoReader{
+		path:        path,
+		streamIndex: stream.Index,
+		info:        info,
+		chunks:      reverseChunks(index, maxFrames),
+	}, nil
+}
+
+// VideoInfo gets information about the current video.
+func (r *ReverseVideoReader) VideoInfo() *VideoInfo {
+	return r.info
+}
+
+// ReadFrame reads the next frame, going backwards from the
+// end of the video.
+//
+// Once the first frame of the video has been returned,
+// nil will be returned along with io.EOF.
+func (r *ReverseVideoReader) ReadFrame() (image.Image, error) {
+	for len(r.frames) == 0 {
+		if len(r.chunks) == 0 {
+			return nil, io.EOF
+		}
+		chunk := r.chunks[len(r.chunks)-1]
+		r.chunks = r.chunks[:len(r.chunks)-1]
+		if err := r.decodeChunk(chunk); err != nil {
+			return nil, errors.Wrap(err, "read frame")
+		}
+	}
+	frame := r.frames[len(r.frames)-1]
+	r.frames[len(r.frames)-1] = nil
+	r.frames = r.frames[:len(r.frames)-1]
+	return frame, nil
+}
+
+// Close releases the decoded frames.
+//
+// No ffmpeg processes remain running between calls to
+// ReadFrame, so this never fails.
+func (r *ReverseVideoReader) Close() error {
+	r.chunks = nil
+	r.frames = nil
+	return nil
+}
+
+func (r *ReverseVideoReader) decodeChunk(chunk *videoChunk) error {
+	reader, err := startVideoReader(r.info, chunk.Args(r.path, r.streamIndex), false)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for i := 0; i < chunk.NumFrames; i++ {
+		frame, err := reader.ReadFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		r.frames = append(r.frames, frame)
+	}
+	return nil
+}
+
+// reverseChunks splits a video into chunks of at most
+// maxFrames frames, each of which is decoded from the
+// keyframe at or before it.
+func reverseChunks(index *frameIndex, maxFrames int) []*videoChunk {
+	var result []*videoChunk
+	for _, gop := range index.Chunks(1) {
+		end := gop.FirstFrame + gop.NumFrames
+		for start := gop.FirstFrame; start < end; start += maxFrames {
+			subEnd := start + maxFrames
+			if subEnd > end {
+				subEnd = end
+			}
+			result = append(result, index.subChunk(gop.FirstFrame, start, subEnd))
+		}
+	}
+	return result
+}
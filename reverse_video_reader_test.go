@@ -0,0 +1,79 @@
+package ffmpego
+
+import (
+	"image"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReverseVideoReader(t *testing.T) {
+	path := filepath.Join("test_data", "test_video.mp4")
+	reader, err := NewReverseVideoReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	forward, err := NewVideoReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer forward.Close()
+	var expected []image.Image
+	for {
+		frame, err := forward.ReadFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, frame)
+	}
+
+	for i := len(expected) - 1; i >= 0; i-- {
+		frame, err := reader.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !imagesEqual(frame, expected[i]) {
+			t.Errorf("frame %d is incorrect", i)
+		}
+	}
+	if _, err := reader.ReadFrame(); err != io.EOF {
+		t.Errorf("expected EOF but got %v", err)
+	}
+}
+
+func TestReverseChunks(t *testing.T) {
+	index, err := parseFrameIndex(strings.Split(testFrameCRCOutput, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := reverseChunks(index, 3)
+	expected := []struct {
+		FirstFrame int
+		NumFrames  int
+		Keyframe   int
+	}{
+		{0, 3, 0},
+		{3, 1, 0},
+		{4, 3, 4},
+		{7, 1, 7},
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("expected %d chunks but got %d", len(expected), len(chunks))
+	}
+	for i, exp := range expected {
+		chunk := chunks[i]
+		keyChunk := index.subChunk(exp.Keyframe, exp.Keyframe, exp.Keyframe+1)
+		if chunk.FirstFrame != exp.FirstFrame || chunk.NumFrames != exp.NumFrames ||
+			chunk.Seek != keyChunk.Seek {
+			t.Errorf("chunk %d: unexpected chunk %+v", i, *chunk)
+		}
+	}
+	if chunks[1].Start != index.midpoint(2, 3) || chunks[1].End != index.midpoint(3, 4) {
+		t.Errorf("unexpected bounds for split chunk: %+v", *chunks[1])
+	}
+}
@@ -0,0 +1,151 @@
+package ffmpego
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A SceneChange is a cut between two shots in a video.
+type SceneChange struct {
+	// Time is the timestamp of the first frame of the new
+	// scene.
+	Time time.Duration
+
+	// Score measures how different the new frame is from
+	// the previous one, from 0 to 1.
+	Score float64
+}
+
+// DetectScenes uses ffmpeg's scene detection to find the
+// cuts in a video.
+//
+// The threshold is a scene score between 0 and 1; frames
+// which differ from the previous frame by more than this
+// are reported as cuts. Values around 0.3 to 0.4 typically
+// work well.
+func DetectScenes(path string, threshold float64) (changes []*SceneChange, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "detect scenes")
+		}
+	}()
+
+	stream, _, err := getVideoStream(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := runFFmpeg(
+		context.Background(),
+		"-i", path,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-filter:v", fmt.Sprintf("select='gt(scene,%f)',metadata=print", threshold),
+		"-f", "null", "-",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return parseSceneChanges(lines)
+}
+
+func parseSceneChanges(lines []string) ([]*SceneChange, error) {
+	frameExp := regexp.MustCompilePOSIX("Parsed_metadata.* pts_time:([^ ]*)")
+	scoreExp := regexp.MustCompilePOSIX("Parsed_metadata.* lavfi\\.scene_score=([^ ]*)")
+
+	var result []*SceneChange
+	var current *SceneChange
+	for _, line := range lines {
+		if match := frameExp.FindStringSubmatch(line); match != nil {
+			timestamp, err := parseSeconds(match[1])
+			if err != nil {
+				return nil, err
+			}
+			current = &SceneChange{Time: timestamp}
+		} else if match := scoreExp.FindStringSubmatch(line); match != nil && current != nil {
+			score, err := strconv.ParseFloat(match[1], 64)
+			if err != nil {
+				return nil, errors.Wrap(err, "parse scene score")
+			}
+			current.Score = score
+			result = append(result, current)
+			current = nil
+		}
+	}
+	return result, nil
+}
+
+// A SceneDetector finds cuts in a video by comparing
+// consecutive frames in Go, allowing custom metrics.
+type SceneDetector struct {
+	// Metric computes the difference between two frames.
+	// If nil, FrameDifference is used.
+	Metric func(prev, cur image.Image) float64
+
+	// Threshold is the minimum value of the metric for a
+	// frame to be considered a cut.
+	Threshold float64
+}
+
+// Detect reads all of the frames from r and returns the
+// detected cuts.
+//
+// Frame timestamps are computed from the frame rate, which
+// is typically taken from the reader's VideoInfo.
+func (s *SceneDetector) Detect(r FrameReader, fps float64) ([]*SceneChange, error) {
+	metric := s.Metric
+	if metric == nil {
+		metric = FrameDifference
+	}
+	var result []*SceneChange
+	var prev image.Image
+	for i := 0; true; i++ {
+		frame, err := r.ReadFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "detect scenes")
+		}
+		if prev != nil {
+			if score := metric(prev, frame); score > s.Threshold {
+				result = append(result, &SceneChange{
+					Time:  time.Duration(float64(i) / fps * float64(time.Second)),
+					Score: score,
+				})
+			}
+		}
+		prev = frame
+	}
+	return result, nil
+}
+
+// FrameDifference computes the mean absolute difference
+// between the color channels of two equally sized images,
+// scaled to the range [0, 1].
+func FrameDifference(img1, img2 image.Image) float64 {
+	b1, b2 := img1.Bounds(), img2.Bounds()
+	if b1.Dx() != b2.Dx() || b1.Dy() != b2.Dy() {
+		panic("image sizes do not match")
+	}
+	var sum float64
+	for y := 0; y < b1.Dy(); y++ {
+		for x := 0; x < b1.Dx(); x++ {
+			r1, g1, bl1, _ := img1.At(b1.Min.X+x, b1.Min.Y+y).RGBA()
+			r2, g2, bl2, _ := img2.At(b2.Min.X+x, b2.Min.Y+y).RGBA()
+			sum += absDiff(r1, r2) + absDiff(g1, g2) + absDiff(bl1, bl2)
+		}
+	}
+	return sum / float64(3*0xffff*b1.Dx()*b1.Dy())
+}
+
+func absDiff(x, y uint32) float64 {
+	if x > y {
+		return float64(x - y)
+	}
+	return float64(y - x)
+}
@@ -0,0 +1,72 @@
+package ffmpego
+
+import (
+	"image"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectScenes(t *testing.T) {
+	changes, err := DetectScenes(filepath.Join("test_data", "test_video.mp4"), 0.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("unexpected scene changes: %v", changes)
+	}
+}
+
+func TestParseSceneChanges(t *testing.T) {
+	log := `[Parsed_metadata_1 @ 0x5591] frame:0    pts:25      pts_time:1.04167
+[Parsed_metadata_1 @ 0x5591] lavfi.scene_score=0.512345
+[Parsed_metadata_1 @ 0x5591] frame:1    pts:75      pts_time:3.125
+[Parsed_metadata_1 @ 0x5591] lavfi.scene_score=0.9
+frame=    2 fps=0.0 q=-0.0 Lsize=N/A time=00:00:03.12 bitrate=N/A speed= 250x`
+	changes, err := parseSceneChanges(strings.Split(log, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []SceneChange{
+		{Time: 1041670 * time.Microsecond, Score: 0.512345},
+		{Time: 3125 * time.Millisecond, Score: 0.9},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes but got %d", len(expected), len(changes))
+	}
+	for i, change := range changes {
+		if *change != expected[i] {
+			t.Errorf("change %d: expected %v but got %v", i, expected[i], *change)
+		}
+	}
+}
+
+func TestSceneDetector(t *testing.T) {
+	reader := &testFrameReader{NumFrames: 10}
+	detector := &SceneDetector{
+		Metric: func(prev, cur image.Image) float64 {
+			if cur.(*image.Gray).Pix[0] == 5 {
+				return 1
+			}
+			return 0
+		},
+		Threshold: 0.5,
+	}
+	changes, err := detector.Detect(reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Time != time.Second/2 || changes[0].Score != 1 {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+}
+
+func TestFrameDifference(t *testing.T) {
+	img1 := image.NewGray(image.Rect(0, 0, 2, 2))
+	img2 := image.NewGray(image.Rect(0, 0, 2, 2))
+	img2.Pix[0] = 0xff
+	if diff := FrameDifference(img1, img2); diff != 0.25 {
+		t.Errorf("unexpected difference: %f", diff)
+	}
+}
@@ -0,0 +1,302 @@
+package ffmpego
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SegmentedVideoWriterOptions configures when a
+// SegmentedVideoWriter starts a new file.
+//
+// At least one of SegmentDuration and SegmentSize must be
+// set.
+type SegmentedVideoWriterOptions struct {
+	// SegmentDuration, if non-zero, is the length of each
+	// segment. Keyframes are placed at multiples of this
+	// duration, so segments are exactly this long.
+	SegmentDuration time.Duration
+
+	// SegmentSize, if non-zero, is the approximate maximum
+	// number of bytes in each segment.
+	//
+	// Segments may be slightly larger than this, since the
+	// encoder buffers some frames.
+	SegmentSize int64
+
+	// Encoding configures the video encoder.
+	// If nil, DefaultVideoEncoding() is used.
+	Encoding *VideoEncoding
+
+	// Metadata and VideoMetadata set tags of every segment.
+	Metadata      Metadata
+	VideoMetadata Metadata
+
+	// Chapters are deliberately not supported. Each segment
+	// is a separate file which may be cut off at any time,
+	// so chapters of the whole recording could not be
+	// divided between them ahead of time. To add chapters,
+	// join the segments with Concat and then use Transcode
+	// with TranscodeOptions.Chapters.
+
+	// OnSegment, if non-nil, is called after each segment is
+	// completed and closed.
+	//
+	// It is called from a separate goroutine, but never
+	// concurrently with itself. All calls have been made by
+	// the time Close returns.
+	OnSegment func(s *Segment)
+}
+
+// A Segment describes a completed file produced by a
+// SegmentedVideoWriter.
+type Segment struct {
+	Path  string
+	Index int
+
+	// Start and End are the timestamps of the segment
+	// within the entire recording.
+	Start time.Duration
+	End   time.Duration
+}
+
+// A SegmentedVideoWriter encodes a continuous recording as
+// a series of files, so that every finished segment is
+// playable even if the recording is never closed.
+type SegmentedVideoWriter struct {
+	pattern string
+	width   int
+	height  int
+	fps     float64
+	opts    SegmentedVideoWriterOptions
+
+	current   *segmentProcess
+	nextIndex int
+	frames    int
+
+	// Serializes calls to OnSegment between processes.
+	callbackLock sync.Mutex
+}
+
+// NewSegmentedVideoWriter creates a SegmentedVideoWriter
+// which writes files named after a printf-style pattern,
+// as in "recording_%05d.mp4".
+//
+// Segments are encoded by ffmpeg's segment muxer. When
+// SegmentSize is used, the muxer is restarted whenever a
+// segment grows too large.
+func NewSegmentedVideoWriter(pattern string, width, height int, fps float64,
+	opts *SegmentedVideoWriterOptions) (*SegmentedVideoWriter, error) {
+	if opts.SegmentDuration <= 0 && opts.SegmentSize <= 0 {
+		return nil, errors.New("write segmented video: either SegmentDuration or SegmentSize " +
+			"must be positive")
+	}
+	if !isPrintfPattern(pattern) {
+		return nil, errors.New("write segmented video: pattern must contain a number, as in %05d")
+	}
+	return &SegmentedVideoWriter{
+		pattern: pattern,
+		width:   width,
+		height:  height,
+		fps:     fps,
+		opts:    *opts,
+	}, nil
+}
+
+// WriteFrame adds a frame to the current segment.
+func (s *SegmentedVideoWriter) WriteFrame(img image.Image) error {
+	if s.current == nil {
+		if err := s.startProcess(); err != nil {
+			return errors.Wrap(err, "write frame")
+		}
+	}
+	if err := s.current.Writer.WriteFrame(img); err != nil {
+		return err
+	}
+	s.frames++
+	if s.opts.SegmentSize > 0 {
+		path := fmt.Sprintf(s.pattern, s.current.CurrentIndex())
+		if info, err := os.Stat(path); err == nil && info.Size() >= s.opts.SegmentSize {
+			if err := s.finishProcess(); err != nil {
+				return errors.Wrap(err, "write frame")
+			}
+		}
+	}
+	return nil
+}
+
+// Close finishes the final segment.
+func (s *SegmentedVideoWriter) Close() error {
+	if s.current == nil {
+		return nil
+	}
+	if err := s.finishProcess(); err != nil {
+		return errors.Wrap(err, "close segmented video writer")
+	}
+	return nil
+}
+
+func (s *SegmentedVideoWriter) startProcess() error {
+	streams, err := createChildStreams(false, true)
+	if err != nil {
+		return err
+	}
+	videoStream, listStream := streams[0], streams[1]
+
+	segmentTime := s.opts.SegmentDuration
+	if segmentTime <= 0 {
+		// Segments are only split by size.
+		segmentTime = 24 * time.Hour * 365
+	}
+	args := append([]string{"-y"}, rawVideoInputArgs(videoStream.ResourceURL(), s.width, s.height,
+		s.fps)...)
+	args = append(args, videoEncodingFlags(s.opts.Encoding)...)
+	if s.opts.SegmentDuration > 0 {
+		args = append(args, alignedKeyframeArgs(s.opts.SegmentDuration)...)
+	}
+	args = append(args, outputMetadataFlags(s.opts.Metadata, s.opts.VideoMetadata, nil)...)
+	args = append(
+		args,
+		"-f", "segment",
+		"-segment_time", fmt.Sprintf("%f", segmentTime.Seconds()),
+		"-segment_start_number", strconv.Itoa(s.nextIndex),
+		"-reset_timestamps", "1",
+		"-segment_list", listStream.ResourceURL(),
+		"-segment_list_type", "csv",
+		s.pattern,
+	)
+
+	cmd := exec.Command("ffmpeg", args...)
+	for _, stream := range streams {
+		cmd.ExtraFiles = append(cmd.ExtraFiles, stream.ExtraFiles()...)
+	}
+	var log bytes.Buffer
+	cmd.Stderr = &log
+	if err := cmd.Start(); err != nil {
+		videoStream.Cancel()
+		listStream.Cancel()
+		return err
+	}
+	writer, err := videoStream.Connect()
+	if err != nil {
+		listStream.Cancel()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	proc := &segmentProcess{
+		Writer: &VideoWriter{
+			command: cmd,
+			writer:  writer,
+			width:   s.width,
+			height:  s.height,
+		},
+		Log:          &log,
+		currentIndex: s.nextIndex,
+		listDone:     make(chan struct{}),
+	}
+	offset := time.Duration(float64(s.frames) / s.fps * float64(time.Second))
+	dir := filepath.Dir(s.pattern)
+	go func() {
+		defer close(proc.listDone)
+		// The list is opened once the first segment starts,
+		// so connecting may block until frames are written.
+		list, err := listStream.Connect()
+		if err != nil {
+			return
+		}
+		defer list.Close()
+		proc.readList(list, func(seg *Segment) {
+			seg.Path = filepath.Join(dir, seg.Path)
+			seg.Start += offset
+			seg.End += offset
+			if s.opts.OnSegment != nil {
+				s.callbackLock.Lock()
+				s.opts.OnSegment(seg)
+				s.callbackLock.Unlock()
+			}
+		})
+	}()
+	s.current = proc
+	return nil
+}
+
+func (s *SegmentedVideoWriter) finishProcess() error {
+	proc := s.current
+	s.current = nil
+	proc.Writer.writer.Close()
+	err := proc.Writer.command.Wait()
+	<-proc.listDone
+
+	// The final segment is listed when the muxer finishes,
+	// so the next process continues after it.
+	s.nextIndex = proc.CurrentIndex()
+
+	if err != nil {
+		return ffmpegError(context.Background(), err, proc.Log.String())
+	}
+	return nil
+}
+
+type segmentProcess struct {
+	Writer *VideoWriter
+	Log    *bytes.Buffer
+
+	lock         sync.Mutex
+	currentIndex int
+	listDone     chan struct{}
+}
+
+// CurrentIndex gets the index of the segment which is
+// currently being written.
+func (s *segmentProcess) CurrentIndex() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.currentIndex
+}
+
+// readList parses the segment list written by the segment
+// muxer, which has one CSV row per completed segment.
+func (s *segmentProcess) readList(r io.Reader, f func(*Segment)) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if _, ok := err.(*csv.ParseError); ok {
+			continue
+		} else if err != nil {
+			break
+		} else if len(record) < 3 {
+			continue
+		}
+		start, err1 := strconv.ParseFloat(record[1], 64)
+		end, err2 := strconv.ParseFloat(record[2], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		s.lock.Lock()
+		index := s.currentIndex
+		s.currentIndex++
+		s.lock.Unlock()
+		f(&Segment{
+			Path:  record[0],
+			Index: index,
+			Start: time.Duration(start * float64(time.Second)),
+			End:   time.Duration(end * float64(time.Second)),
+		})
+	}
+	io.Copy(ioutil.Discard, r)
+}
@@ -0,0 +1,92 @@
+package ffmpego
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSegmentProcessReadList(t *testing.T) {
+	list := "out_003.mp4,0.000000,1.000000\n" +
+		"\"out,004.mp4\",1.000000,2.500000\n"
+	proc := &segmentProcess{currentIndex: 3}
+	var segments []*Segment
+	proc.readList(strings.NewReader(list), func(s *Segment) {
+		segments = append(segments, s)
+	})
+	expected := []*Segment{
+		{Path: "out_003.mp4", Index: 3, Start: 0, End: time.Second},
+		{Path: "out,004.mp4", Index: 4, Start: time.Second, End: 2500 * time.Millisecond},
+	}
+	if len(segments) != len(expected) {
+		t.Fatalf("expected %d segments but got %d", len(expected), len(segments))
+	}
+	for i, s := range segments {
+		if *s != *expected[i] {
+			t.Errorf("segment %d: expected %+v but got %+v", i, *expected[i], *s)
+		}
+	}
+	if proc.CurrentIndex() != 5 {
+		t.Errorf("unexpected current index: %d", proc.CurrentIndex())
+	}
+}
+
+func TestSegmentedVideoWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-segmented-video-writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var segments []*Segment
+	vw, err := NewSegmentedVideoWriter(filepath.Join(dir, "out_%03d.mp4"), 50, 50, 12,
+		&SegmentedVideoWriterOptions{
+			SegmentDuration: time.Second,
+			OnSegment: func(s *Segment) {
+				segments = append(segments, s)
+			},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 24; i++ {
+		frame := image.NewGray(image.Rect(0, 0, 50, 50))
+		if err := vw.WriteFrame(frame); err != nil {
+			vw.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := vw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(segments) != 2 {
+		t.Fatalf("expected 2 segments but got %d", len(segments))
+	}
+	for i, s := range segments {
+		if s.Index != i {
+			t.Errorf("segment %d has index %d", i, s.Index)
+		}
+		if s.Start != time.Duration(i)*time.Second {
+			t.Errorf("segment %d starts at %v", i, s.Start)
+		}
+		info, err := GetMediaInfo(s.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Duration < 900*time.Millisecond || info.Duration > 1100*time.Millisecond {
+			t.Errorf("segment %d has duration %v", i, info.Duration)
+		}
+	}
+}
+
+func TestSegmentedVideoWriterBadOptions(t *testing.T) {
+	_, err := NewSegmentedVideoWriter("out_%03d.mp4", 8, 8, 12, &SegmentedVideoWriterOptions{})
+	if err == nil {
+		t.Error("expected an error without SegmentDuration or SegmentSize")
+	}
+}
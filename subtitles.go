@@ -0,0 +1,344 @@
+package ffmpego
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A SubtitleCue is a piece of text which is shown during a
+// range of time.
+type SubtitleCue struct {
+	Start time.Duration
+	End   time.Duration
+
+	// Text is the text of the cue, which may have multiple
+	// lines and simple formatting tags such as <i>.
+	Text string
+}
+
+// A SubtitleTrack is a subtitle stream to add to an
+// output file.
+type SubtitleTrack struct {
+	// File is a subtitle file, such as an SRT, WebVTT, or
+	// ASS file.
+	File string
+
+	// Cues are used for the track if File is empty.
+	Cues []*SubtitleCue
+
+	// Language is the language tag of the track, such as
+	// "eng".
+	Language string
+
+	// Title is the name of the track shown by players.
+	Title string
+
+	// Default marks the track to be shown by default.
+	Default bool
+}
+
+// ReadSubtitles extracts the cues of the first subtitle
+// stream in a media file or subtitle file.
+//
+// Text subtitles of any format supported by ffmpeg, such as
+// SRT, WebVTT, ASS and MP4 text, can be read. Styling which
+// cannot be represented as SRT is removed.
+func ReadSubtitles(path string) ([]*SubtitleCue, error) {
+	return ReadSubtitlesStream(path, nil)
+}
+
+// ReadSubtitlesStream is like ReadSubtitles, but reads the
+// first subtitle stream which matches the selector.
+func ReadSubtitlesStream(path string, selector StreamSelector) (cues []*SubtitleCue, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "read subtitles")
+		}
+	}()
+	info, err := getMediaInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := info.selectStream(StreamTypeSubtitle, selector)
+	if err != nil {
+		return nil, err
+	}
+	// The output is parsed as-is, since splitting it into
+	// lines would lose the blank lines between cues.
+	var stdout, stderr bytes.Buffer
+	err = runFFmpegOutput(
+		context.Background(), &stdout, &stderr,
+		"-i", path,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-c:s", "srt", "-f", "srt", "pipe:1",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSRT(&stdout)
+}
+
+// ParseSRT parses cues from a SubRip file.
+func ParseSRT(r io.Reader) ([]*SubtitleCue, error) {
+	cues, err := parseCueBlocks(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse SRT")
+	}
+	return cues, nil
+}
+
+// ParseWebVTT parses cues from a WebVTT file.
+//
+// Cue settings, such as positions, are discarded, as are
+// comments and style blocks.
+func ParseWebVTT(r io.Reader) ([]*SubtitleCue, error) {
+	cues, err := parseCueBlocks(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse WebVTT")
+	}
+	return cues, nil
+}
+
+// WriteSRT encodes cues as a SubRip file.
+func WriteSRT(w io.Writer, cues []*SubtitleCue) error {
+	bw := bufio.NewWriter(w)
+	for i, cue := range cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1, formatCueTime(cue.Start, ','),
+			formatCueTime(cue.End, ','), cueText(cue.Text))
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "write SRT")
+	}
+	return nil
+}
+
+// WriteWebVTT encodes cues as a WebVTT file.
+func WriteWebVTT(w io.Writer, cues []*SubtitleCue) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		fmt.Fprintf(bw, "%s --> %s\n%s\n\n", formatCueTime(cue.Start, '.'),
+			formatCueTime(cue.End, '.'), cueText(cue.Text))
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "write WebVTT")
+	}
+	return nil
+}
+
+// parseCueBlocks parses the blank-line separated blocks of
+// an SRT or WebVTT file, skipping blocks without a timing
+// line, such as headers and comments.
+func parseCueBlocks(r io.Reader) ([]*SubtitleCue, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var cues []*SubtitleCue
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 || strings.HasPrefix(lines[0], "NOTE") {
+			continue
+		}
+		parts := strings.SplitN(lines[timing], "-->", 2)
+		start, err := parseCueTime(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		// Settings may follow the end time in WebVTT.
+		endFields := strings.Fields(parts[1])
+		if len(endFields) == 0 {
+			return nil, errors.New("missing end time: " + lines[timing])
+		}
+		end, err := parseCueTime(endFields[0])
+		if err != nil {
+			return nil, err
+		}
+		cues = append(cues, &SubtitleCue{
+			Start: start,
+			End:   end,
+			Text:  strings.Join(lines[timing+1:], "\n"),
+		})
+	}
+	return cues, nil
+}
+
+// parseCueTime parses a timestamp such as "01:02:03,456"
+// or "02:03.456".
+func parseCueTime(s string) (time.Duration, error) {
+	s = strings.Replace(strings.TrimSpace(s), ",", ".", 1)
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, errors.New("invalid timestamp: " + s)
+	}
+	seconds, err := parseSeconds(parts[len(parts)-1])
+	if err != nil {
+		return 0, err
+	}
+	result := seconds
+	for i, unit := range []time.Duration{time.Minute, time.Hour}[:len(parts)-1] {
+		n, err := strconv.Atoi(parts[len(parts)-2-i])
+		if err != nil {
+			return 0, errors.New("invalid timestamp: " + s)
+		}
+		result += time.Duration(n) * unit
+	}
+	return result, nil
+}
+
+func formatCueTime(t time.Duration, separator rune) string {
+	ms := int64(t / time.Millisecond)
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, (ms/60000)%60, (ms/1000)%60, separator,
+		ms%1000)
+}
+
+// cueText removes blank lines from the text of a cue, since
+// they would end the cue early.
+func cueText(text string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// subtitleFile gets the path of a file for the track,
+// writing its cues to a temporary SRT file if necessary.
+//
+// If temp is true, the caller must remove the file.
+func (s *SubtitleTrack) subtitleFile() (path string, temp bool, err error) {
+	if s.File != "" {
+		return s.File, false, nil
+	}
+	f, err := ioutil.TempFile("", "ffmpego-subtitles*.srt")
+	if err != nil {
+		return "", false, err
+	}
+	err = WriteSRT(f, s.Cues)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", false, err
+	}
+	return f.Name(), true, nil
+}
+
+// subtitleInputs gets the input files for subtitle tracks,
+// as well as any temporary files which the caller must
+// remove, even if an error is returned.
+func subtitleInputs(tracks []*SubtitleTrack) (paths, tempFiles []string, err error) {
+	for _, track := range tracks {
+		path, temp, err := track.subtitleFile()
+		if err != nil {
+			return nil, tempFiles, err
+		}
+		if temp {
+			tempFiles = append(tempFiles, path)
+		}
+		paths = append(paths, path)
+	}
+	return paths, tempFiles, nil
+}
+
+// subtitleOutputArgs creates output flags which add soft
+// subtitle tracks from inputs starting at firstInput.
+func subtitleOutputArgs(tracks []*SubtitleTrack, firstInput int, output string) []string {
+	var args []string
+	for i := range tracks {
+		args = append(args, "-map", fmt.Sprintf("%d:s:0", firstInput+i))
+	}
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".mp4", ".m4v", ".mov":
+		args = append(args, "-c:s", "mov_text")
+	case ".webm":
+		args = append(args, "-c:s", "webvtt")
+	case ".mkv":
+		args = append(args, "-c:s", "copy")
+	}
+	for i, track := range tracks {
+		spec := ":s:s:" + strconv.Itoa(i)
+		metadata := Metadata{}
+		if track.Language != "" {
+			metadata["language"] = track.Language
+		}
+		if track.Title != "" {
+			metadata["title"] = track.Title
+		}
+		args = append(args, metadataFlags(spec, metadata)...)
+		disposition := "0"
+		if track.Default {
+			disposition = "default"
+		}
+		args = append(args, "-disposition:s:"+strconv.Itoa(i), disposition)
+	}
+	return args
+}
+
+// subtitlesFilter creates a filter which draws the
+// subtitles from a file onto a video.
+func subtitlesFilter(path string) string {
+	name := "subtitles"
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ass", ".ssa":
+		name = "ass"
+	}
+	return name + "=filename=" + escapeFilterValue(path)
+}
+
+// textSubtitleCodecs are the subtitle codecs which can be
+// converted to mov_text.
+var textSubtitleCodecs = map[string]bool{
+	"subrip": true, "srt": true, "ass": true, "ssa": true, "mov_text": true, "webvtt": true,
+	"text": true, "microdvd": true, "subviewer": true, "subviewer1": true, "realtext": true,
+	"sami": true, "stl": true, "mpl2": true, "pjs": true, "vplayer": true, "jacosub": true,
+}
+
+// subtitleCopyArgs maps the subtitle streams of the first
+// input which can be stored in an output file, along with
+// the codec flags for them.
+//
+// MP4 and MOV files only support mov_text subtitles, so
+// text subtitles are converted, and bitmap subtitles such
+// as PGS and DVD subtitles are dropped.
+func subtitleCopyArgs(info *MediaInfo, output string) []string {
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".mp4", ".m4v", ".mov":
+	default:
+		return []string{"-map", "0:s?", "-c:s", "copy"}
+	}
+	var args []string
+	for _, stream := range info.Streams {
+		if stream.Type == StreamTypeSubtitle && textSubtitleCodecs[stream.Codec] {
+			args = append(args, "-map", fmt.Sprintf("0:%d", stream.Index))
+		}
+	}
+	if args != nil {
+		args = append(args, "-c:s", "mov_text")
+	}
+	return args
+}
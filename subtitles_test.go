@@ -0,0 +1,195 @@
+package ffmpego
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSRT(t *testing.T) {
+	data := "\ufeff1\r\n00:00:01,000 --> 00:00:02,500\r\nHello\r\nworld\r\n\r\n" +
+		"2\r\n01:02:03,004 --> 01:02:04,000 X1:10\r\n<i>Second</i>\r\n"
+	cues, err := ParseSRT(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []SubtitleCue{
+		{Start: time.Second, End: 2500 * time.Millisecond, Text: "Hello\nworld"},
+		{
+			Start: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond,
+			End:   time.Hour + 2*time.Minute + 4*time.Second,
+			Text:  "<i>Second</i>",
+		},
+	}
+	checkCues(t, cues, expected)
+}
+
+func TestParseWebVTT(t *testing.T) {
+	data := "WEBVTT - Test\n\n" +
+		"NOTE a comment\n--> with an arrow\n\n" +
+		"STYLE\n::cue { color: red }\n\n" +
+		"intro\n00:01.000 --> 00:02.000 line:0 align:start\nFirst\n\n" +
+		"00:00:03.250 --> 00:00:04.000\nSecond\n"
+	cues, err := ParseWebVTT(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []SubtitleCue{
+		{Start: time.Second, End: 2 * time.Second, Text: "First"},
+		{Start: 3250 * time.Millisecond, End: 4 * time.Second, Text: "Second"},
+	}
+	checkCues(t, cues, expected)
+}
+
+func TestWriteSubtitles(t *testing.T) {
+	cues := []*SubtitleCue{
+		{Start: 1500 * time.Millisecond, End: 3 * time.Second, Text: "One\n\nTwo"},
+		{Start: time.Hour, End: time.Hour + time.Second, Text: "Three"},
+	}
+
+	var srt bytes.Buffer
+	if err := WriteSRT(&srt, cues); err != nil {
+		t.Fatal(err)
+	}
+	expected := "1\n00:00:01,500 --> 00:00:03,000\nOne\nTwo\n\n" +
+		"2\n01:00:00,000 --> 01:00:01,000\nThree\n\n"
+	if srt.String() != expected {
+		t.Errorf("unexpected SRT:\n%s", srt.String())
+	}
+
+	var vtt bytes.Buffer
+	if err := WriteWebVTT(&vtt, cues); err != nil {
+		t.Fatal(err)
+	}
+	expected = "WEBVTT\n\n00:00:01.500 --> 00:00:03.000\nOne\nTwo\n\n" +
+		"01:00:00.000 --> 01:00:01.000\nThree\n\n"
+	if vtt.String() != expected {
+		t.Errorf("unexpected WebVTT:\n%s", vtt.String())
+	}
+
+	parsed, err := ParseWebVTT(&vtt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCues(t, parsed, []SubtitleCue{
+		{Start: 1500 * time.Millisecond, End: 3 * time.Second, Text: "One\nTwo"},
+		{Start: time.Hour, End: time.Hour + time.Second, Text: "Three"},
+	})
+}
+
+func TestSubtitleOutputArgs(t *testing.T) {
+	tracks := []*SubtitleTrack{
+		{File: "a.srt", Language: "eng", Title: "English", Default: true},
+		{File: "b.srt"},
+	}
+	actual := subtitleOutputArgs(tracks, 2, "out.mp4")
+	expected := []string{
+		"-map", "2:s:0", "-map", "3:s:0",
+		"-c:s", "mov_text",
+		"-metadata:s:s:0", "language=eng", "-metadata:s:s:0", "title=English",
+		"-disposition:s:0", "default",
+		"-disposition:s:1", "0",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected args: %v", actual)
+	}
+
+	opts := &TranscodeOptions{}
+	actual = opts.videoFlags("subs/a:b.ass")
+	expected = []string{"-c:v", "libx264", "-preset", "fast", "-crf", "18", "-pix_fmt", "yuv420p",
+		"-filter:v", `ass=filename=subs/a\\:b.ass,pad=ceil(iw/2)*2:ceil(ih/2)*2`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected burn flags: %v", actual)
+	}
+}
+
+func TestSubtitleTracks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-subtitle-tracks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cues := []*SubtitleCue{
+		{Start: 0, End: time.Second, Text: "Hello"},
+		{Start: time.Second, End: 2 * time.Second, Text: "multi-line\nworld"},
+	}
+	outPath := filepath.Join(dir, "out.mkv")
+	vw, err := NewVideoWriterWithOptions(outPath, 64, 64, 12, &VideoWriterOptions{
+		Subtitles: []*SubtitleTrack{{Cues: cues, Language: "eng"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 24; i++ {
+		if err := vw.WriteFrame(image.NewGray(image.Rect(0, 0, 64, 64))); err != nil {
+			vw.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := vw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := ReadSubtitlesStream(outPath, StreamWithLanguage("eng"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCues(t, actual, []SubtitleCue{*cues[0], *cues[1]})
+
+	// Burning subtitles should re-encode the video.
+	burnedPath := filepath.Join(dir, "burned.mp4")
+	err = Transcode(context.Background(), outPath, burnedPath, &TranscodeOptions{
+		BurnSubtitles: &SubtitleTrack{Cues: cues},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := GetMediaInfo(burnedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Streams) != 1 || info.Streams[0].Type != StreamTypeVideo {
+		t.Errorf("unexpected streams in burned output: %v", info.Streams)
+	}
+}
+
+func checkCues(t *testing.T, actual []*SubtitleCue, expected []SubtitleCue) {
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d cues but got %d", len(expected), len(actual))
+	}
+	for i, cue := range actual {
+		if *cue != expected[i] {
+			t.Errorf("cue %d: expected %+v but got %+v", i, expected[i], *cue)
+		}
+	}
+}
+
+func TestSubtitleCopyArgs(t *testing.T) {
+	info := &MediaInfo{Streams: []*StreamInfo{
+		{Index: 0, Type: StreamTypeVideo, Codec: "h264"},
+		{Index: 1, Type: StreamTypeSubtitle, Codec: "hdmv_pgs_subtitle"},
+		{Index: 2, Type: StreamTypeSubtitle, Codec: "subrip"},
+	}}
+	actual := subtitleCopyArgs(info, "out.MP4")
+	expected := []string{"-map", "0:2", "-c:s", "mov_text"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected MP4 args: %v", actual)
+	}
+	actual = subtitleCopyArgs(info, "out.mkv")
+	expected = []string{"-map", "0:s?", "-c:s", "copy"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected Matroska args: %v", actual)
+	}
+	info.Streams = info.Streams[:2]
+	if actual := subtitleCopyArgs(info, "out.mov"); actual != nil {
+		t.Errorf("bitmap subtitles should be dropped: %v", actual)
+	}
+}
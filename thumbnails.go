@@ -0,0 +1,94 @@
+package ffmpego
+
+import (
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ExtractFrame decodes the frame of a video which is shown
+// at the given timestamp.
+//
+// The file is seeked to the nearest keyframe, so only a
+// small part of the video needs to be decoded.
+func ExtractFrame(path string, timestamp time.Duration) (frame *TimedFrame, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "extract frame")
+		}
+	}()
+	return extractFrame(path, timestamp)
+}
+
+// ExtractThumbnails decodes n frames which are evenly
+// spaced throughout a video.
+//
+// Each frame is taken from the middle of an equal-length
+// section of the video, so the very first and last frames
+// are never used.
+func ExtractThumbnails(path string, n int) (frames []*TimedFrame, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "extract thumbnails")
+		}
+	}()
+
+	info, err := getMediaInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Duration == 0 {
+		return nil, errors.New("unknown video duration")
+	}
+	for i := 0; i < n; i++ {
+		timestamp := time.Duration((float64(i) + 0.5) * float64(info.Duration) / float64(n))
+		frame, err := extractFrame(path, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// ExtractKeyframes decodes every keyframe in a video.
+//
+// Frames which are not keyframes are skipped without being
+// decoded, making this much faster than decoding the whole
+// video.
+func ExtractKeyframes(path string) (frames []*TimedFrame, err error) {
+	reader, err := NewVideoReaderWithOptions(path, &VideoReaderOptions{
+		KeyframesOnly: true,
+		Timestamps:    true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "extract keyframes")
+	}
+	defer reader.Close()
+	for {
+		frame, err := reader.ReadTimedFrame()
+		if err == io.EOF {
+			return frames, nil
+		} else if err != nil {
+			return nil, errors.Wrap(err, "extract keyframes")
+		}
+		frames = append(frames, frame)
+	}
+}
+
+func extractFrame(path string, timestamp time.Duration) (*TimedFrame, error) {
+	reader, err := newVideoReader(path, &VideoReaderOptions{
+		Start:      timestamp,
+		Timestamps: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	frame, err := reader.ReadTimedFrame()
+	if err == io.EOF {
+		return nil, errors.Errorf("no frame at timestamp %v", timestamp)
+	}
+	return frame, err
+}
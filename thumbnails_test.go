@@ -0,0 +1,54 @@
+package ffmpego
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExtractFrame(t *testing.T) {
+	frame, err := ExtractFrame(filepath.Join("test_data", "test_video.mp4"), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Image.Bounds().Dx() != 64 || frame.Image.Bounds().Dy() != 32 {
+		t.Error("bad frame bounds:", frame.Image.Bounds())
+	}
+	if frame.Time < time.Second || frame.Time > time.Second+time.Second/12 {
+		t.Errorf("unexpected frame time: %v", frame.Time)
+	}
+}
+
+func TestExtractThumbnails(t *testing.T) {
+	frames, err := ExtractThumbnails(filepath.Join("test_data", "test_video.mp4"), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 4 {
+		t.Fatalf("expected 4 frames but got %d", len(frames))
+	}
+	for i := 1; i < len(frames); i++ {
+		if frames[i].Time <= frames[i-1].Time {
+			t.Errorf("frame times should be increasing: %v, %v", frames[i-1].Time, frames[i].Time)
+		}
+	}
+}
+
+func TestExtractKeyframes(t *testing.T) {
+	frames, err := ExtractKeyframes(filepath.Join("test_data", "test_video.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyframes, err := GetKeyframes(filepath.Join("test_data", "test_video.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != len(keyframes) {
+		t.Fatalf("expected %d keyframes but got %d", len(keyframes), len(frames))
+	}
+	for i, frame := range frames {
+		if diff := frame.Time - keyframes[i]; diff > time.Millisecond || diff < -time.Millisecond {
+			t.Errorf("keyframe %d: expected time %v but got %v", i, keyframes[i], frame.Time)
+		}
+	}
+}
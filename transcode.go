@@ -0,0 +1,299 @@
+package ffmpego
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TranscodeOptions configures how Transcode converts a
+// file.
+type TranscodeOptions struct {
+	// Video configures the video encoder.
+	//
+	// If nil, the video is copied without re-encoding it,
+	// unless it must be re-encoded to apply the scaling or
+	// frame rate options, in which case
+	// DefaultVideoEncoding() is used.
+	//
+	// A Codec of "copy" cannot be combined with options
+	// which filter the video, such as Width or
+	// BurnSubtitles.
+	Video *VideoEncoding
+
+	// Audio configures the audio encoder.
+	// If nil, the audio is copied without re-encoding it.
+	Audio *AudioEncoding
+
+	// Width and Height, if non-zero, scale the video.
+	// If only one of them is set, the other is chosen to
+	// preserve the aspect ratio.
+	Width  int
+	Height int
+
+	// FPS, if non-zero, changes the frame rate of the video.
+	FPS float64
+
+	// VideoStream and AudioStream choose which streams to
+	// take from the input. If nil, the first stream of each
+	// type is used, if there is one.
+	VideoStream StreamSelector
+	AudioStream StreamSelector
+
+	// NoVideo and NoAudio drop the video or audio from the
+	// output.
+	NoVideo bool
+	NoAudio bool
+
+	// Metadata sets global tags of the output. Tags which
+	// are not set are copied from the input.
+	Metadata Metadata
+
+	// VideoMetadata and AudioMetadata set tags of the output
+	// video and audio streams.
+	VideoMetadata Metadata
+	AudioMetadata Metadata
+
+	// Chapters, if non-empty, replaces the chapters of the
+	// input. Otherwise, chapters are copied from the input.
+	Chapters []*Chapter
+
+	// Subtitles are added to the output as soft subtitle
+	// tracks. Subtitles from the input are not copied.
+	Subtitles []*SubtitleTrack
+
+	// BurnSubtitles, if non-nil, draws subtitles onto the
+	// video, which must then be re-encoded. Only the File
+	// or Cues of the track are used.
+	//
+	// ASS files keep their styling, while other formats are
+	// drawn with a default style.
+	BurnSubtitles *SubtitleTrack
+
+	// Progress, if non-nil, is called periodically while
+	// the file is being transcoded.
+	Progress func(p *TranscodeProgress)
+}
+
+// TranscodeProgress describes how much of a file has been
+// transcoded.
+type TranscodeProgress struct {
+	// Frame is the number of video frames written so far.
+	Frame int
+
+	// Time is the timestamp of the output so far.
+	Time time.Duration
+
+	// Duration is the duration of the input, or 0 if it is
+	// unknown.
+	Duration time.Duration
+
+	// Speed is the rate of transcoding relative to
+	// real-time playback.
+	Speed float64
+
+	// Done is true for the final progress update.
+	Done bool
+}
+
+// Fraction gets the fraction of the input that has been
+// transcoded, or 0 if the duration is unknown.
+func (t *TranscodeProgress) Fraction() float64 {
+	if t.Duration <= 0 {
+		return 0
+	}
+	frac := float64(t.Time) / float64(t.Duration)
+	if frac > 1 || t.Done {
+		frac = 1
+	}
+	return frac
+}
+
+// Transcode converts a media file to a different format
+// using a single ffmpeg process, without passing any frames
+// through Go.
+//
+// The format of the output is determined by the extension
+// of the output path.
+func Transcode(ctx context.Context, input, output string, opts *TranscodeOptions) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "transcode")
+		}
+	}()
+
+	if opts.Video != nil && opts.Video.Codec == "copy" &&
+		(opts.Width != 0 || opts.Height != 0 || opts.FPS != 0 || opts.BurnSubtitles != nil) {
+		return errors.New("video cannot be scaled, resampled, or have subtitles burned in " +
+			"when it is copied")
+	}
+
+	info, err := getMediaInfo(input)
+	if err != nil {
+		return err
+	}
+	args := []string{"-y", "-i", input}
+	inputIndex := 1
+	var chapterFlags []string
+	if len(opts.Chapters) > 0 {
+		chapterFile, err := writeChapterFile(opts.Chapters)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(chapterFile)
+		var chapterInput []string
+		chapterInput, chapterFlags = chapterInputArgs(chapterFile, inputIndex)
+		args = append(args, chapterInput...)
+		inputIndex++
+	}
+	subtitlePaths, tempFiles, err := subtitleInputs(opts.Subtitles)
+	defer removeTempFiles(tempFiles)
+	if err != nil {
+		return err
+	}
+	for _, path := range subtitlePaths {
+		args = append(args, "-i", path)
+	}
+	var burnPath string
+	if opts.BurnSubtitles != nil {
+		var temp bool
+		burnPath, temp, err = opts.BurnSubtitles.subtitleFile()
+		if err != nil {
+			return err
+		}
+		if temp {
+			defer os.Remove(burnPath)
+		}
+	}
+
+	if !opts.NoVideo {
+		stream, err := selectOptionalStream(info, StreamTypeVideo, opts.VideoStream)
+		if err != nil {
+			return err
+		}
+		if stream != nil {
+			args = append(args, "-map", fmt.Sprintf("0:%d", stream.Index))
+			args = append(args, opts.videoFlags(burnPath)...)
+			args = append(args, metadataFlags(":s:v:0", opts.VideoMetadata)...)
+		}
+	}
+	if !opts.NoAudio {
+		stream, err := selectOptionalStream(info, StreamTypeAudio, opts.AudioStream)
+		if err != nil {
+			return err
+		}
+		if stream != nil {
+			args = append(args, "-map", fmt.Sprintf("0:%d", stream.Index))
+			if opts.Audio == nil {
+				args = append(args, "-c:a", "copy")
+			} else {
+				args = append(args, opts.Audio.flags()...)
+			}
+			args = append(args, metadataFlags(":s:a:0", opts.AudioMetadata)...)
+		}
+	}
+	if !containsString(args, "-map") {
+		return errors.New("no streams to transcode")
+	}
+	args = append(args, subtitleOutputArgs(opts.Subtitles, inputIndex, output)...)
+	args = append(args, chapterFlags...)
+	args = append(args, metadataFlags("", opts.Metadata)...)
+	args = append(args, output)
+
+	if opts.Progress == nil {
+		_, err = runFFmpeg(ctx, args...)
+		return err
+	}
+	_, err = runFFmpegProgress(ctx, func(values map[string]string) {
+		opts.Progress(parseTranscodeProgress(values, info.Duration))
+	}, args...)
+	return err
+}
+
+// videoFlags creates the output flags for the video, with
+// an optional subtitle file to burn into it.
+func (t *TranscodeOptions) videoFlags(subtitlesPath string) []string {
+	encoding := t.Video
+	if encoding == nil {
+		if t.Width == 0 && t.Height == 0 && t.FPS == 0 && subtitlesPath == "" {
+			return []string{"-c:v", "copy"}
+		}
+		encoding = DefaultVideoEncoding()
+	}
+
+	var filters []string
+	if t.Width != 0 || t.Height != 0 {
+		// Negative sizes preserve the aspect ratio, and -2
+		// also rounds to an even number.
+		auto := -1
+		if encoding.needsEvenSize() {
+			auto = -2
+		}
+		width, height := t.Width, t.Height
+		if width == 0 {
+			width = auto
+		}
+		if height == 0 {
+			height = auto
+		}
+		filters = append(filters, fmt.Sprintf("scale=%d:%d", width, height))
+	}
+	if t.FPS != 0 {
+		filters = append(filters, fmt.Sprintf("fps=%f", t.FPS))
+	}
+	if subtitlesPath != "" {
+		filters = append(filters, subtitlesFilter(subtitlesPath))
+	}
+	if encoding.needsEvenSize() {
+		filters = append(filters, "pad=ceil(iw/2)*2:ceil(ih/2)*2")
+	}
+
+	flags := encoding.flags()
+	if len(filters) > 0 {
+		flags = append(flags, "-filter:v", strings.Join(filters, ","))
+	}
+	return flags
+}
+
+// selectOptionalStream selects a stream of the given type,
+// returning nil if there is none and no selector was
+// specified.
+func selectOptionalStream(info *MediaInfo, t StreamType, selector StreamSelector) (*StreamInfo, error) {
+	stream, err := info.selectStream(t, selector)
+	if err != nil && selector == nil {
+		return nil, nil
+	}
+	return stream, err
+}
+
+func parseTranscodeProgress(values map[string]string, duration time.Duration) *TranscodeProgress {
+	result := &TranscodeProgress{
+		Duration: duration,
+		Done:     values["progress"] == "end",
+	}
+	if frame, err := strconv.Atoi(values["frame"]); err == nil {
+		result.Frame = frame
+	}
+	if us, err := strconv.ParseInt(values["out_time_us"], 10, 64); err == nil && us > 0 {
+		result.Time = time.Duration(us) * time.Microsecond
+	}
+	speed := strings.TrimSuffix(values["speed"], "x")
+	if s, err := strconv.ParseFloat(strings.TrimSpace(speed), 64); err == nil {
+		result.Speed = s
+	}
+	return result
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package ffmpego
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTranscode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-transcode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outPath := filepath.Join(dir, "out.mp4")
+	var updates []*TranscodeProgress
+	err = Transcode(context.Background(), filepath.Join("test_data", "test_video.mp4"), outPath,
+		&TranscodeOptions{
+			Video:    &VideoEncoding{Codec: "libx264", CRF: 30, PixelFormat: "yuv420p"},
+			Height:   16,
+			FPS:      6,
+			Progress: func(p *TranscodeProgress) { updates = append(updates, p) },
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := GetVideoInfo(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Width != 32 || info.Height != 16 || info.FPS != 6 {
+		t.Errorf("unexpected video info: %#v", info)
+	}
+	if len(updates) == 0 {
+		t.Fatal("no progress updates")
+	}
+	if last := updates[len(updates)-1]; !last.Done || last.Frame != 12 || last.Fraction() != 1 {
+		t.Errorf("unexpected final progress: %#v", last)
+	}
+}
+
+func TestTranscodeCopy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-transcode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inPath := filepath.Join("test_data", "test_video.mp4")
+	outPath := filepath.Join(dir, "out.mkv")
+	if err := Transcode(context.Background(), inPath, outPath, &TranscodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := GetKeyframes(inPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := GetKeyframes(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected keyframes %v but got %v", expected, actual)
+	}
+
+	err = Transcode(context.Background(), inPath, outPath, &TranscodeOptions{NoVideo: true})
+	if err == nil {
+		t.Error("expected error when no streams are selected")
+	}
+}
+
+func TestTranscodeVideoFlags(t *testing.T) {
+	tests := []struct {
+		Options  TranscodeOptions
+		Expected []string
+	}{
+		{
+			TranscodeOptions{},
+			[]string{"-c:v", "copy"},
+		},
+		{
+			TranscodeOptions{Width: 1280},
+			[]string{"-c:v", "libx264", "-preset", "fast", "-crf", "18", "-pix_fmt", "yuv420p",
+				"-filter:v", "scale=1280:-2,pad=ceil(iw/2)*2:ceil(ih/2)*2"},
+		},
+		{
+			TranscodeOptions{
+				Video:  &VideoEncoding{Codec: "libvpx-vp9", Bitrate: 1000000, KeyframeInterval: 48},
+				Height: 720,
+				FPS:    24,
+			},
+			[]string{"-c:v", "libvpx-vp9", "-b:v", "1000000", "-g", "48",
+				"-filter:v", "scale=-1:720,fps=24.000000"},
+		},
+	}
+	for i, test := range tests {
+		actual := test.Options.videoFlags("")
+		if !reflect.DeepEqual(actual, test.Expected) {
+			t.Errorf("test %d: expected %v but got %v", i, test.Expected, actual)
+		}
+	}
+}
+
+func TestTranscodeCopyWithFilters(t *testing.T) {
+	for _, opts := range []*TranscodeOptions{
+		{Video: &VideoEncoding{Codec: "copy"}, Width: 32},
+		{Video: &VideoEncoding{Codec: "copy"}, FPS: 24},
+		{Video: &VideoEncoding{Codec: "copy"}, BurnSubtitles: &SubtitleTrack{File: "a.srt"}},
+	} {
+		if err := Transcode(context.Background(), "in.mp4", "out.mp4", opts); err == nil ||
+			!strings.Contains(err.Error(), "copied") {
+			t.Errorf("unexpected error for %+v: %v", *opts, err)
+		}
+	}
+}
+
+func TestParseTranscodeProgress(t *testing.T) {
+	values := map[string]string{
+		"frame":       "120",
+		"out_time_us": "5000000",
+		"speed":       " 2.5x",
+		"progress":    "continue",
+	}
+	actual := parseTranscodeProgress(values, 10*time.Second)
+	expected := &TranscodeProgress{
+		Frame:    120,
+		Time:     5 * time.Second,
+		Duration: 10 * time.Second,
+		Speed:    2.5,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v but got %#v", expected, actual)
+	}
+	if actual.Fraction() != 0.5 {
+		t.Errorf("unexpected fraction: %f", actual.Fraction())
+	}
+
+	values = map[string]string{"out_time_us": "N/A", "speed": "N/A", "progress": "end"}
+	actual = parseTranscodeProgress(values, 0)
+	if !actual.Done || actual.Time != 0 || actual.Fraction() != 0 {
+		t.Errorf("unexpected progress: %#v", actual)
+	}
+}
@@ -0,0 +1,470 @@
+package ffmpego
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A TrimMode determines how Trim cuts a video without
+// re-encoding all of it.
+type TrimMode int
+
+const (
+	// TrimKeyframe copies the video without re-encoding it,
+	// moving the start of the cut back to the nearest
+	// keyframe so that the output can be decoded.
+	TrimKeyframe TrimMode = iota
+
+	// TrimSmart cuts at the exact frames requested by
+	// re-encoding the partial groups of pictures at the
+	// edges of the cut and copying everything between them.
+	//
+	// This is only supported for H.264 and HEVC video. The
+	// edges are encoded with the pixel format, profile, and
+	// level of the source, and trimming fails if the encoder
+	// cannot match them.
+	TrimSmart
+)
+
+// smartTrimEncoders maps codecs to encoders which can
+// produce video that can be joined with copied packets.
+var smartTrimEncoders = map[string]string{
+	"h264": "libx264",
+	"hevc": "libx265",
+}
+
+// smartTrimProfiles maps the profiles which ffmpeg reports
+// for each codec to the corresponding encoder profiles.
+var smartTrimProfiles = map[string]map[string]string{
+	"h264": {
+		"Constrained Baseline":  "baseline",
+		"Baseline":              "baseline",
+		"Main":                  "main",
+		"High":                  "high",
+		"High 10":               "high10",
+		"High 4:2:2":            "high422",
+		"High 4:4:4 Predictive": "high444",
+	},
+	"hevc": {
+		"Main":    "main",
+		"Main 10": "main10",
+	},
+}
+
+// Trim copies the range of a file between start and end to
+// a new file.
+//
+// Timestamps are relative to the first frame of the video.
+// If end is 0, the output continues to the end of the
+// input.
+//
+// Audio is copied without re-encoding it. Only the first
+// video and audio stream are used.
+func Trim(ctx context.Context, input, output string, start, end time.Duration,
+	mode TrimMode) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "trim")
+		}
+	}()
+	if end != 0 && end <= start {
+		return errors.New("end must come after start")
+	}
+
+	info, err := getMediaInfo(input)
+	if err != nil {
+		return err
+	}
+	video, err := selectOptionalStream(info, StreamTypeVideo, nil)
+	if err != nil {
+		return err
+	}
+	if video == nil {
+		return trimAudio(ctx, input, output, start, end)
+	}
+	index, err := probeFrameIndex(input, video.Index)
+	if err != nil {
+		return err
+	}
+	plan := planTrim(index, start, end)
+	if plan.NumFrames() == 0 {
+		return errors.New("no frames in range")
+	}
+
+	switch mode {
+	case TrimKeyframe:
+		return trimKeyframe(ctx, input, output, video.Index, index, plan)
+	case TrimSmart:
+		if _, ok := smartTrimEncoders[video.Codec]; !ok {
+			return errors.New("smart trimming is not supported for codec: " + video.Codec)
+		}
+		level, err := probeCodecLevel(ctx, input, video)
+		if err != nil {
+			return err
+		}
+		encoding, err := smartTrimEncoding(video, level)
+		if err != nil {
+			return err
+		}
+		return trimSmart(ctx, input, output, video, encoding, index, plan)
+	default:
+		panic(fmt.Sprintf("unknown trim mode: %d", mode))
+	}
+}
+
+// Remux copies the streams of a file into a different
+// container without re-encoding them.
+//
+// The output format is determined by the extension of the
+// output path. Text subtitles are converted to mov_text
+// for MP4 and MOV outputs, which do not support other
+// formats, and bitmap subtitles are dropped from them.
+func Remux(ctx context.Context, input, output string) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "remux")
+		}
+	}()
+	info, err := getMediaInfo(input)
+	if err != nil {
+		return err
+	}
+	_, err = runFFmpeg(ctx, remuxArgs(info, nil, input, output)...)
+	return err
+}
+
+// remuxArgs creates the arguments for Remux, with extra
+// flags for the input.
+func remuxArgs(info *MediaInfo, inputFlags []string, input, output string) []string {
+	args := append([]string{"-y"}, inputFlags...)
+	args = append(
+		args,
+		"-i", input,
+		"-map", "0:v?", "-map", "0:a?",
+	)
+	args = append(args, subtitleCopyArgs(info, output)...)
+	args = append(args, "-c:v", "copy", "-c:a", "copy")
+	return append(args, output)
+}
+
+// A trimPlan splits the frames of a trimmed video into a
+// head and tail which must be re-encoded, and a middle
+// which starts and ends at keyframes.
+//
+// Each part is a range of frame indices [start, end),
+// which is empty if start == end.
+type trimPlan struct {
+	Start       int
+	MiddleStart int
+	MiddleEnd   int
+	End         int
+}
+
+func planTrim(index *frameIndex, start, end time.Duration) *trimPlan {
+	firstFrame := index.Seconds(0)
+	frameAt := func(t time.Duration) int {
+		abs := firstFrame + t.Seconds()
+		for i := range index.PTS {
+			// Allow for rounding in the requested time.
+			if index.Seconds(i) >= abs-1e-6 {
+				return i
+			}
+		}
+		return len(index.PTS)
+	}
+
+	plan := &trimPlan{Start: frameAt(start), End: len(index.PTS)}
+	if end != 0 {
+		plan.End = frameAt(end)
+	}
+	plan.MiddleStart = plan.End
+	for i := plan.Start; i < plan.End; i++ {
+		if index.Key[i] {
+			plan.MiddleStart = i
+			break
+		}
+	}
+	plan.MiddleEnd = plan.MiddleStart
+	if plan.End == len(index.PTS) {
+		plan.MiddleEnd = plan.End
+	} else {
+		for i := plan.End; i > plan.MiddleStart; i-- {
+			if index.Key[i] {
+				plan.MiddleEnd = i
+				break
+			}
+		}
+	}
+	return plan
+}
+
+// NumFrames gets the total number of frames in the output.
+func (t *trimPlan) NumFrames() int {
+	return t.End - t.Start
+}
+
+// KeyframeStart gets the index of the last keyframe at or
+// before the start of the cut.
+func (t *trimPlan) KeyframeStart(index *frameIndex) int {
+	for i := t.Start; i > 0; i-- {
+		if index.Key[i] {
+			return i
+		}
+	}
+	return 0
+}
+
+func trimKeyframe(ctx context.Context, input, output string, streamIndex int, index *frameIndex,
+	plan *trimPlan) error {
+	keyframe := plan.KeyframeStart(index)
+	args := []string{"-y"}
+	args = append(args, seekArgs(index, keyframe)...)
+	args = append(
+		args,
+		"-i", input,
+		"-map", fmt.Sprintf("0:%d", streamIndex), "-map", "0:a:0?",
+	)
+	if plan.End < len(index.PTS) {
+		// The output starts at the seek position, which lies
+		// between the keyframe and the next frame.
+		seek := index.Seconds(keyframe)
+		if keyframe > 0 && keyframe+1 < len(index.PTS) {
+			seek = index.midpoint(keyframe, keyframe+1)
+		}
+		duration := index.midpoint(plan.End-1, plan.End) - seek
+		args = append(args, "-t", fmt.Sprintf("%f", duration))
+	}
+	args = append(args, "-c", "copy", "-avoid_negative_ts", "make_zero", output)
+	_, err := runFFmpeg(ctx, args...)
+	return err
+}
+
+func trimSmart(ctx context.Context, input, output string, video *StreamInfo,
+	encoding *VideoEncoding, index *frameIndex, plan *trimPlan) error {
+	streamIndex := video.Index
+
+	tempDir, err := ioutil.TempDir("", "ffmpego-trim")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Parts are stored as MPEG-TS, which keeps codec
+	// parameters in-band so that the re-encoded parts can be
+	// joined with the copied one.
+	var parts []string
+	addPart := func(args ...string) error {
+		partPath := filepath.Join(tempDir, fmt.Sprintf("part_%d.ts", len(parts)))
+		args = append([]string{"-y"}, args...)
+		args = append(args, "-f", "mpegts", partPath)
+		if _, err := runFFmpeg(ctx, args...); err != nil {
+			return err
+		}
+		parts = append(parts, partPath)
+		return nil
+	}
+	// Encoders may silently convert to a different pixel
+	// format or profile, which would make the parts
+	// incompatible.
+	checkEncodedPart := func() error {
+		info, err := getMediaInfo(parts[len(parts)-1])
+		if err != nil {
+			return err
+		}
+		part, err := info.selectStream(StreamTypeVideo, nil)
+		if err != nil {
+			return err
+		}
+		if part.Video.PixelFormat != video.Video.PixelFormat || part.Profile != video.Profile {
+			return errors.Errorf("encoder produced %s (%s) instead of %s (%s)",
+				part.Video.PixelFormat, part.Profile, video.Video.PixelFormat, video.Profile)
+		}
+		return nil
+	}
+
+	if plan.Start < plan.MiddleStart {
+		chunk := index.subChunk(plan.KeyframeStart(index), plan.Start, plan.MiddleStart)
+		args := append(chunk.Args(input, streamIndex), encoding.flags()...)
+		if err := addPart(args...); err != nil {
+			return err
+		}
+		if err := checkEncodedPart(); err != nil {
+			return err
+		}
+	}
+	if plan.MiddleStart < plan.MiddleEnd {
+		pattern := filepath.Join(strings.ReplaceAll(tempDir, "%", "%%"), "middle_%d.ts")
+		args := copiedPartArgs(index, plan, input, streamIndex, pattern)
+		if _, err := runFFmpeg(ctx, args...); err != nil {
+			return err
+		}
+		parts = append(parts, fmt.Sprintf(pattern, 0))
+	}
+	if plan.MiddleEnd < plan.End {
+		chunk := index.subChunk(plan.MiddleEnd, plan.MiddleEnd, plan.End)
+		args := append(chunk.Args(input, streamIndex), encoding.flags()...)
+		if err := addPart(args...); err != nil {
+			return err
+		}
+		if err := checkEncodedPart(); err != nil {
+			return err
+		}
+	}
+
+	listPath := filepath.Join(tempDir, "parts.txt")
+	if err := writeConcatList(listPath, parts); err != nil {
+		return err
+	}
+	args := []string{"-y", "-f", "concat", "-safe", "0", "-i", listPath}
+	if plan.Start > 0 {
+		args = append(args, "-seek_timestamp", "1", "-ss", fmt.Sprintf("%f", index.Seconds(plan.Start)))
+	}
+	if plan.End < len(index.PTS) {
+		duration := index.Seconds(plan.End) - index.Seconds(plan.Start)
+		args = append(args, "-t", fmt.Sprintf("%f", duration))
+	}
+	args = append(
+		args,
+		"-i", input,
+		"-map", "0:v", "-map", "1:a:0?",
+		"-c", "copy", "-avoid_negative_ts", "make_zero", output,
+	)
+	_, err = runFFmpeg(ctx, args...)
+	return err
+}
+
+// copiedPartArgs creates the arguments for copying the
+// keyframe-aligned middle of a smart trim to MPEG-TS files
+// named after a printf-style pattern. Only the first file
+// is part of the trim.
+//
+// Stream copy applies -t and -to by decode timestamp, which
+// would also copy the keyframe that starts the tail, along
+// with frames that are reordered after it. Instead, the
+// segment muxer splits the packets at that keyframe by
+// presentation timestamp.
+func copiedPartArgs(index *frameIndex, plan *trimPlan, input string, streamIndex int,
+	pattern string) []string {
+	args := append([]string{"-y"}, seekArgs(index, plan.MiddleStart)...)
+	args = append(
+		args,
+		// Timestamps are preserved so that they can be
+		// compared to the frame index.
+		"-copyts", "-i", input,
+		"-map", fmt.Sprintf("0:%d", streamIndex),
+		"-c:v", "copy",
+	)
+	if plan.MiddleEnd == len(index.PTS) {
+		return append(args, "-f", "mpegts", fmt.Sprintf(pattern, 0))
+	}
+	return append(
+		args,
+		"-f", "segment", "-segment_format", "mpegts",
+		"-segment_times", fmt.Sprintf("%f", index.midpoint(plan.MiddleEnd-1, plan.MiddleEnd)),
+		"-reset_timestamps", "0",
+		pattern,
+	)
+}
+
+// smartTrimEncoding creates an encoding for the edges of a
+// smart trim which matches the parameters of the source
+// video, so that the edges can be joined with copied
+// packets.
+func smartTrimEncoding(video *StreamInfo, level string) (*VideoEncoding, error) {
+	profile, ok := smartTrimProfiles[video.Codec][video.Profile]
+	if !ok {
+		return nil, errors.Errorf("smart trimming is not supported for %s profile: %q",
+			video.Codec, video.Profile)
+	}
+	if video.Video.PixelFormat == "" {
+		return nil, errors.New("smart trimming requires a known pixel format")
+	}
+	encoding := DefaultVideoEncoding()
+	encoding.Codec = smartTrimEncoders[video.Codec]
+	encoding.PixelFormat = video.Video.PixelFormat
+	encoding.ExtraFlags = []string{"-profile:v", profile}
+	if video.Codec == "hevc" {
+		encoding.ExtraFlags = append(encoding.ExtraFlags, "-x265-params", "level-idc="+level)
+	} else {
+		encoding.ExtraFlags = append(encoding.ExtraFlags, "-level:v", level)
+	}
+	return encoding, nil
+}
+
+// probeCodecLevel finds the level of an H.264 or HEVC
+// stream, such as "4.1", from its parameter sets.
+func probeCodecLevel(ctx context.Context, path string, video *StreamInfo) (string, error) {
+	lines, err := runFFmpeg(
+		ctx,
+		"-i", path,
+		"-map", fmt.Sprintf("0:%d", video.Index),
+		"-c:v", "copy", "-bsf:v", "trace_headers",
+		"-frames:v", "1", "-f", "null", "-",
+	)
+	if err != nil {
+		return "", err
+	}
+	return parseCodecLevel(lines, video.Codec)
+}
+
+// parseCodecLevel parses the level from the log of the
+// trace_headers bitstream filter.
+func parseCodecLevel(lines []string, codec string) (string, error) {
+	field := "level_idc"
+	if codec == "hevc" {
+		field = "general_level_idc"
+	}
+	exp := regexp.MustCompile("\\s" + field + "\\s.*= ([0-9]+)")
+	for _, line := range lines {
+		match := exp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		idc, err := strconv.Atoi(match[1])
+		if err != nil {
+			return "", errors.Wrap(err, "parse level")
+		}
+		if codec == "hevc" {
+			// HEVC levels are multiplied by 30.
+			return fmt.Sprintf("%d.%d", idc/30, (idc%30)/3), nil
+		} else if idc == 9 {
+			return "1b", nil
+		}
+		return fmt.Sprintf("%d.%d", idc/10, idc%10), nil
+	}
+	return "", errors.New("could not find level of " + codec + " stream")
+}
+
+// seekArgs gets input flags for seeking to the keyframe at
+// or before a frame.
+func seekArgs(index *frameIndex, frame int) []string {
+	if frame == 0 {
+		return nil
+	}
+	// Seek between frames so that rounding cannot move the
+	// seek before the target frame.
+	seek := index.Seconds(frame)
+	if frame+1 < len(index.PTS) {
+		seek = index.midpoint(frame, frame+1)
+	}
+	return []string{"-seek_timestamp", "1", "-ss", fmt.Sprintf("%f", seek)}
+}
+
+func trimAudio(ctx context.Context, input, output string, start, end time.Duration) error {
+	args := []string{"-y", "-ss", formatSeconds(start), "-i", input}
+	if end != 0 {
+		args = append(args, "-t", formatSeconds(end-start))
+	}
+	args = append(args, "-map", "0:a:0", "-c", "copy", output)
+	_, err := runFFmpeg(ctx, args...)
+	return err
+}
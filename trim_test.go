@@ -0,0 +1,182 @@
+package ffmpego
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrim(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-trim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inPath := filepath.Join(dir, "in.mp4")
+	encoding := DefaultVideoEncoding()
+	encoding.KeyframeInterval = 6
+	encoding.ExtraFlags = []string{"-sc_threshold", "0"}
+	err = Transcode(context.Background(), filepath.Join("test_data", "test_video.mp4"), inPath,
+		&TranscodeOptions{Video: encoding})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frameDuration := time.Second / 12
+	tests := []struct {
+		Mode   TrimMode
+		Frames int
+	}{
+		// The keyframe cut starts at frame 0.
+		{TrimKeyframe, 21},
+		{TrimSmart, 18},
+	}
+	for _, test := range tests {
+		outPath := filepath.Join(dir, "out.mp4")
+		err := Trim(context.Background(), inPath, outPath, 3*frameDuration, 21*frameDuration,
+			test.Mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index, err := probeFrameIndex(outPath, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(index.PTS) != test.Frames {
+			t.Errorf("mode %d: expected %d frames but got %d", test.Mode, test.Frames,
+				len(index.PTS))
+		}
+		// Frames at the seams must not be duplicated or lost.
+		for i := 1; i < len(index.PTS); i++ {
+			delta := index.Seconds(i) - index.Seconds(i-1)
+			if math.Abs(delta-frameDuration.Seconds()) > frameDuration.Seconds()/4 {
+				t.Errorf("mode %d: frame %d is %f seconds after the previous one", test.Mode, i,
+					delta)
+			}
+		}
+	}
+}
+
+func TestRemux(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-remux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outPath := filepath.Join(dir, "out.mkv")
+	if err := Remux(context.Background(), filepath.Join("test_data", "test_video.mp4"),
+		outPath); err != nil {
+		t.Fatal(err)
+	}
+	index, err := probeFrameIndex(outPath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.PTS) != 24 {
+		t.Errorf("expected 24 frames but got %d", len(index.PTS))
+	}
+}
+
+func TestPlanTrim(t *testing.T) {
+	index, err := parseFrameIndex(strings.Split(testFrameCRCOutput, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frameDuration := time.Second / 12
+	tests := []struct {
+		Start    time.Duration
+		End      time.Duration
+		Expected trimPlan
+		Keyframe int
+	}{
+		{0, 0, trimPlan{Start: 0, MiddleStart: 0, MiddleEnd: 8, End: 8}, 0},
+		{frameDuration, 6 * frameDuration, trimPlan{Start: 1, MiddleStart: 4, MiddleEnd: 4, End: 6}, 0},
+		{2 * frameDuration, 7 * frameDuration, trimPlan{Start: 2, MiddleStart: 4, MiddleEnd: 7, End: 7}, 0},
+		{5 * frameDuration, 6 * frameDuration, trimPlan{Start: 5, MiddleStart: 6, MiddleEnd: 6, End: 6}, 4},
+	}
+	for i, test := range tests {
+		plan := planTrim(index, test.Start, test.End)
+		if *plan != test.Expected {
+			t.Errorf("test %d: expected %+v but got %+v", i, test.Expected, *plan)
+		}
+		if k := plan.KeyframeStart(index); k != test.Keyframe {
+			t.Errorf("test %d: expected keyframe %d but got %d", i, test.Keyframe, k)
+		}
+	}
+}
+
+func TestCopiedPartArgs(t *testing.T) {
+	index, err := parseFrameIndex(strings.Split(testFrameCRCOutput, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &trimPlan{Start: 2, MiddleStart: 4, MiddleEnd: 7, End: 7}
+	actual := copiedPartArgs(index, plan, "in.mp4", 0, "middle_%d.ts")
+	expected := append([]string{"-y"}, seekArgs(index, 4)...)
+	expected = append(expected, "-copyts", "-i", "in.mp4", "-map", "0:0", "-c:v", "copy",
+		"-f", "segment", "-segment_format", "mpegts",
+		"-segment_times", fmt.Sprintf("%f", index.midpoint(6, 7)),
+		"-reset_timestamps", "0", "middle_%d.ts")
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected args: %v", actual)
+	}
+
+	plan = &trimPlan{Start: 0, MiddleStart: 0, MiddleEnd: 8, End: 8}
+	actual = copiedPartArgs(index, plan, "in.mp4", 0, "middle_%d.ts")
+	expected = []string{"-y", "-copyts", "-i", "in.mp4", "-map", "0:0", "-c:v", "copy",
+		"-f", "mpegts", "middle_0.ts"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected args: %v", actual)
+	}
+}
+
+func TestSmartTrimEncoding(t *testing.T) {
+	log := "[trace_headers @ 0x55d1] Sequence Parameter Set\n" +
+		"[trace_headers @ 0x55d1] 0           forbidden_zero_bit                                          0 = 0\n" +
+		"[trace_headers @ 0x55d1] 24          level_idc                                            00011111 = 31\n"
+	level, err := parseCodecLevel(strings.Split(log, "\n"), "h264")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level != "3.1" {
+		t.Errorf("unexpected H.264 level: %s", level)
+	}
+	log = "[trace_headers @ 0x55d1] 44          general_level_idc                                    01011101 = 93\n" +
+		"[trace_headers @ 0x55d1] 90          sub_layer_level_idc[0]                               00111100 = 60\n"
+	level, err = parseCodecLevel(strings.Split(log, "\n"), "hevc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level != "3.1" {
+		t.Errorf("unexpected HEVC level: %s", level)
+	}
+
+	video := &StreamInfo{
+		Codec:   "h264",
+		Profile: "High 10",
+		Video:   &VideoInfo{PixelFormat: "yuv420p10le"},
+	}
+	encoding, err := smartTrimEncoding(video, "4.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"-c:v", "libx264", "-preset", "fast", "-crf", "18", "-pix_fmt", "yuv420p10le",
+		"-profile:v", "high10", "-level:v", "4.0"}
+	if actual := encoding.flags(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected flags: %v", actual)
+	}
+
+	video.Profile = "High 10 Intra"
+	if _, err := smartTrimEncoding(video, "4.0"); err == nil {
+		t.Error("expected error for unsupported profile")
+	}
+}
@@ -1,7 +1,6 @@
 package ffmpego
 
 import (
-	"os"
 	"os/exec"
 	"regexp"
 	"strconv"
@@ -15,70 +14,123 @@ type VideoInfo struct {
 	Width  int
 	Height int
 	FPS    float64
+
+	// PixelFormat is the name of ffmpeg's pixel format for
+	// the decoded frames, such as "yuv420p".
+	PixelFormat string
+
+	// TimeScale is the number of timestamp ticks per second,
+	// i.e. the reciprocal of the stream's time base.
+	TimeScale float64
 }
 
 // GetVideoInfo gets information about a video file.
 func GetVideoInfo(path string) (info *VideoInfo, err error) {
+	return GetVideoInfoStream(path, nil)
+}
+
+// GetVideoInfoStream gets information about a video stream
+// in a file.
+//
+// If selector is nil, the first video stream is used.
+func GetVideoInfoStream(path string, selector StreamSelector) (info *VideoInfo, err error) {
 	defer func() {
 		if err != nil {
 			err = errors.Wrap(err, "get video info")
 		}
 	}()
 
-	// Make sure file exists so we can give a clean error
-	// message in this case, instead of depending on ffmpeg.
-	if _, err := os.Stat(path); err != nil {
-		return nil, err
+	_, info, err = getVideoStream(path, selector)
+	return info, err
+}
+
+func getVideoStream(path string, selector StreamSelector) (*StreamInfo, *VideoInfo, error) {
+	stream, info, err := getVariableVideoStream(path, selector)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.FPS == 0 {
+		return nil, nil, errors.New("could not find fps in output")
 	}
+	return stream, info, nil
+}
 
-	lines, err := infoOutputLines(path)
+// getVariableVideoStream is like getVideoStream, but allows
+// the frame rate to be unknown, as it may be for variable
+// frame rate inputs like animated images.
+func getVariableVideoStream(path string, selector StreamSelector) (*StreamInfo, *VideoInfo, error) {
+	mediaInfo, err := getMediaInfo(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	stream, err := mediaInfo.selectStream(StreamTypeVideo, selector)
+	if err != nil {
+		return nil, nil, err
+	}
+	if stream.Video.Width == 0 || stream.Video.Height == 0 {
+		return nil, nil, errors.New("could not find dimensions in output")
 	}
+	return stream, stream.Video, nil
+}
 
-	var foundFPS, foundSize bool
+// parseVideoStream parses the description of a video
+// stream from ffmpeg's output.
+//
+// Values which are not found are left as zero.
+func parseVideoStream(desc string) (*VideoInfo, error) {
 	result := &VideoInfo{}
 
 	fpsExp := regexp.MustCompilePOSIX(" ([0-9\\.]*) fps,")
 	sizeExp := regexp.MustCompilePOSIX(" ([0-9]+)x([0-9]+)(,| )")
-	for _, line := range lines {
-		if !strings.Contains(line, "Video:") {
-			continue
+	pixFmtExp := regexp.MustCompilePOSIX("^[^,]*, ([a-z][a-z0-9_]*)(\\(|,)")
+	timeScaleExp := regexp.MustCompilePOSIX(" ([0-9\\.]+)(k|m)? tbn")
+	if match := fpsExp.FindStringSubmatch(desc); match != nil {
+		fps, err := strconv.ParseFloat(match[1], 0)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse FPS")
 		}
-		if match := fpsExp.FindStringSubmatch(line); match != nil {
-			foundFPS = true
-			fps, err := strconv.ParseFloat(match[1], 0)
+		result.FPS = fps
+	}
+	if match := sizeExp.FindStringSubmatch(desc); match != nil {
+		var size [2]int
+		for i, s := range match[1:3] {
+			n, err := strconv.Atoi(s)
 			if err != nil {
-				return nil, errors.Wrap(err, "parse FPS")
+				return nil, errors.Wrap(err, "parse dimensions")
 			}
-			result.FPS = fps
-		}
-		if match := sizeExp.FindStringSubmatch(line); match != nil {
-			foundSize = true
-			var size [2]int
-			for i, s := range match[1:3] {
-				n, err := strconv.Atoi(s)
-				if err != nil {
-					return nil, errors.Wrap(err, "parse dimensions")
-				}
-				size[i] = n
-			}
-			result.Width = size[0]
-			result.Height = size[1]
+			size[i] = n
 		}
+		result.Width = size[0]
+		result.Height = size[1]
 	}
-
-	if !foundFPS {
-		return nil, errors.New("could not find fps in output")
+	if match := pixFmtExp.FindStringSubmatch(desc); match != nil {
+		result.PixelFormat = match[1]
 	}
-	if !foundSize {
-		return nil, errors.New("could not find dimensions in output")
+	if match := timeScaleExp.FindStringSubmatch(desc); match != nil {
+		scale, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse time base")
+		}
+		switch match[2] {
+		case "k":
+			scale *= 1e3
+		case "m":
+			scale *= 1e6
+		}
+		result.TimeScale = scale
 	}
+
 	return result, nil
 }
 
 func infoOutputLines(path string) ([]string, error) {
-	cmd := exec.Command("ffmpeg", "-i", path)
+	return inputInfoLines("-i", path)
+}
+
+// inputInfoLines gets ffmpeg's description of an input
+// which may require extra input flags, such as a format.
+func inputInfoLines(inputArgs ...string) ([]string, error) {
+	cmd := exec.Command("ffmpeg", inputArgs...)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		// An error exit status is expected, since we didn't do any
@@ -1,11 +1,17 @@
 package ffmpego
 
 import (
+	"bufio"
 	"fmt"
 	"image"
 	"image/color"
 	"io"
+	"io/ioutil"
 	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -15,14 +21,31 @@ type VideoReader struct {
 	command *exec.Cmd
 	reader  io.ReadCloser
 	info    *VideoInfo
+
+	// Only used when timestamps are enabled.
+	start         time.Duration
+	timestamps    <-chan *frameTimestamp
+	stopLog       chan struct{}
+	logFinished   <-chan struct{}
+	lastTimestamp *frameTimestamp
+}
+
+// A TimedFrame is a decoded frame along with its timing
+// information.
+type TimedFrame struct {
+	Image image.Image
+
+	// Time is the presentation timestamp of the frame,
+	// relative to the start of the file.
+	Time time.Duration
+
+	// Duration is the amount of time the frame should be
+	// displayed for, or 0 if it is unknown.
+	Duration time.Duration
 }
 
 func NewVideoReader(path string) (*VideoReader, error) {
-	vr, err := newVideoReader(path, -1)
-	if err != nil {
-		err = errors.Wrap(err, "read video")
-	}
-	return vr, err
+	return NewVideoReaderWithOptions(path, &VideoReaderOptions{})
 }
 
 // NewVideoReaderResampled creates a VideoReader that
@@ -31,39 +54,140 @@ func NewVideoReaderResampled(path string, fps float64) (*VideoReader, error) {
 	if fps <= 0 {
 		panic("FPS must be positive")
 	}
-	vr, err := newVideoReader(path, fps)
+	return NewVideoReaderWithOptions(path, &VideoReaderOptions{FPS: fps})
+}
+
+// VideoReaderOptions configures how a VideoReader decodes
+// a file.
+type VideoReaderOptions struct {
+	// Stream chooses which video stream to decode.
+	// If nil, the first video stream is used.
+	Stream StreamSelector
+
+	// FPS, if non-zero, is the frame rate to resample the
+	// video to.
+	FPS float64
+
+	// Start, if non-zero, is the timestamp to seek to before
+	// decoding. Seeking is fast, since the file is not
+	// decoded before the keyframe preceding Start.
+	Start time.Duration
+
+	// Duration, if non-zero, limits how much of the video
+	// is decoded.
+	Duration time.Duration
+
+	// KeyframesOnly causes all frames except keyframes to
+	// be skipped without being decoded.
+	KeyframesOnly bool
+
+	// Timestamps enables ReadTimedFrame.
+	//
+	// When this is set, frames are returned exactly as they
+	// are decoded unless FPS is also set, so frames are not
+	// duplicated or dropped to achieve a constant frame
+	// rate.
+	//
+	// This can be used to read animated GIF, APNG, and WebP
+	// files with the delay of every frame. Frames are
+	// composited by ffmpeg, so each one is a complete image.
+	// Animated WebP decoding requires ffmpeg 7.1 or later.
+	Timestamps bool
+}
+
+// NewVideoReaderWithOptions creates a VideoReader with
+// custom decoding options.
+func NewVideoReaderWithOptions(path string, opts *VideoReaderOptions) (*VideoReader, error) {
+	if opts.FPS < 0 {
+		panic("FPS must not be negative")
+	}
+	vr, err := newVideoReader(path, opts)
 	if err != nil {
 		err = errors.Wrap(err, "read video")
 	}
 	return vr, err
 }
 
-func newVideoReader(path string, resampleFPS float64) (*VideoReader, error) {
-	info, err := GetVideoInfo(path)
+func newVideoReader(path string, opts *VideoReaderOptions) (*VideoReader, error) {
+	getStream := getVideoStream
+	if opts.Timestamps && opts.FPS == 0 {
+		// Variable frame rate inputs can be read when frames
+		// are passed through with their timestamps.
+		getStream = getVariableVideoStream
+	}
+	streamInfo, info, err := getStream(path, opts.Stream)
 	if err != nil {
 		return nil, err
 	}
 
-	if resampleFPS > 0 {
-		info.FPS = resampleFPS
+	if opts.FPS > 0 {
+		info.FPS = opts.FPS
 	}
 
-	stream, err := CreateChildStream(true)
+	var args []string
+	if opts.KeyframesOnly {
+		args = append(args, "-skip_frame", "nokey")
+	}
+	if opts.Start != 0 {
+		args = append(args, "-ss", formatSeconds(opts.Start))
+	}
+	args = append(
+		args,
+		"-i", path,
+		"-map", fmt.Sprintf("0:%d", streamInfo.Index),
+	)
+	if opts.Duration != 0 {
+		args = append(args, "-t", formatSeconds(opts.Duration))
+	}
+	var filters []string
+	if opts.FPS > 0 {
+		filters = append(filters, fmt.Sprintf("fps=fps=%f", opts.FPS))
+	}
+	if opts.Timestamps {
+		filters = append(filters, "showinfo")
+	}
+	if len(filters) > 0 {
+		args = append(args, "-filter:v", strings.Join(filters, ","))
+	}
+	if opts.KeyframesOnly || opts.Timestamps {
+		args = append(args, "-vsync", "passthrough")
+	}
+	vr, err := startVideoReader(info, args, opts.Timestamps)
 	if err != nil {
 		return nil, err
 	}
+	vr.start = opts.Start
+	return vr, nil
+}
 
-	args := []string{
-		"-i", path,
-		"-f", "rawvideo", "-pix_fmt", "rgb24",
-	}
-	if resampleFPS > 0 {
-		args = append(args, "-filter:v", fmt.Sprintf("fps=fps=%f", resampleFPS))
+// startVideoReader runs ffmpeg with the given input and
+// filtering arguments, and decodes the resulting frames,
+// which must be of the size specified by info.
+//
+// If timestamps is true, the arguments must include a
+// showinfo filter, which is used to track the timestamp of
+// every frame.
+func startVideoReader(info *VideoInfo, args []string, timestamps bool) (*VideoReader, error) {
+	stream, err := CreateChildStream(true)
+	if err != nil {
+		return nil, err
 	}
-	args = append(args, stream.ResourceURL())
+
+	args = append(
+		append([]string{}, args...),
+		"-f", "rawvideo", "-pix_fmt", "rgb24", stream.ResourceURL(),
+	)
 
 	cmd := exec.Command("ffmpeg", args...)
 	cmd.ExtraFiles = stream.ExtraFiles()
+	var logReader io.ReadCloser
+	if timestamps {
+		logReader, err = cmd.StderrPipe()
+		if err != nil {
+			stream.Cancel()
+			return nil, err
+		}
+	}
 	if err := cmd.Start(); err != nil {
 		stream.Cancel()
 		return nil, err
@@ -73,11 +197,24 @@ func newVideoReader(path string, resampleFPS float64) (*VideoReader, error) {
 		cmd.Process.Kill()
 		return nil, err
 	}
-	return &VideoReader{
+	res := &VideoReader{
 		command: cmd,
 		reader:  reader,
 		info:    info,
-	}, nil
+	}
+	if timestamps {
+		timestampCh := make(chan *frameTimestamp, 16)
+		logFinished := make(chan struct{})
+		res.timestamps = timestampCh
+		res.stopLog = make(chan struct{})
+		res.logFinished = logFinished
+		go func() {
+			defer close(logFinished)
+			defer close(timestampCh)
+			parseShowinfoLog(logReader, timestampCh, res.stopLog)
+		}()
+	}
+	return res, nil
 }
 
 // VideoInfo gets information about the current video.
@@ -94,6 +231,12 @@ func (v *VideoReader) ReadFrame() (image.Image, error) {
 	if _, err := io.ReadFull(v.reader, buf); err != nil {
 		return nil, err
 	}
+	if v.timestamps != nil {
+		v.lastTimestamp = <-v.timestamps
+		if v.lastTimestamp == nil {
+			return nil, errors.New("read frame: missing timestamp")
+		}
+	}
 	img := image.NewRGBA(image.Rect(0, 0, v.info.Width, v.info.Height))
 	for y := 0; y < v.info.Height; y++ {
 		for x := 0; x < v.info.Width; x++ {
@@ -110,12 +253,97 @@ func (v *VideoReader) ReadFrame() (image.Image, error) {
 	return img, nil
 }
 
+// ReadTimedFrame is like ReadFrame, but also returns the
+// timing information of the frame.
+//
+// The reader must have been created with the Timestamps
+// option.
+func (v *VideoReader) ReadTimedFrame() (*TimedFrame, error) {
+	if v.timestamps == nil {
+		panic("timestamps were not enabled for this reader")
+	}
+	img, err := v.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+	return &TimedFrame{
+		Image:    img,
+		Time:     v.start + v.lastTimestamp.Time,
+		Duration: v.lastTimestamp.Duration,
+	}, nil
+}
+
 // Close stops the decoding process and closes all
 // associated files.
 func (v *VideoReader) Close() error {
+	if v.stopLog != nil {
+		close(v.stopLog)
+	}
 	// When we close the pipe, the subprocess should terminate
 	// (possibly with an error) because it cannot write.
 	v.reader.Close()
+	if v.logFinished != nil {
+		<-v.logFinished
+	}
 	v.command.Wait()
 	return nil
 }
+
+type frameTimestamp struct {
+	Time     time.Duration
+	Duration time.Duration
+}
+
+// parseShowinfoLog reads the log of an ffmpeg process and
+// sends the timestamp of every frame reported by the
+// showinfo filter to ch.
+//
+// Once stop is closed, the log is still read to the end,
+// but timestamps are discarded.
+func parseShowinfoLog(r io.Reader, ch chan<- *frameTimestamp, stop <-chan struct{}) {
+	timeBaseExp := regexp.MustCompilePOSIX("config in time_base: ([0-9]+)/([0-9]+)")
+	frameExp := regexp.MustCompilePOSIX(" n: *[0-9]+ pts: *(-?[0-9]+) pts_time:([^ ]*)")
+	durationExp := regexp.MustCompilePOSIX(" duration: *([0-9]+)")
+
+	var timeBase float64
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	stopped := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if stopped || !strings.Contains(line, "Parsed_showinfo") {
+			continue
+		}
+		if match := timeBaseExp.FindStringSubmatch(line); match != nil {
+			num, _ := strconv.ParseFloat(match[1], 64)
+			den, _ := strconv.ParseFloat(match[2], 64)
+			if den != 0 {
+				timeBase = num / den
+			}
+			continue
+		}
+		match := frameExp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		ts := &frameTimestamp{}
+		if timeBase != 0 {
+			pts, _ := strconv.ParseFloat(match[1], 64)
+			ts.Time = time.Duration(pts * timeBase * float64(time.Second))
+			if match := durationExp.FindStringSubmatch(line); match != nil {
+				duration, _ := strconv.ParseFloat(match[1], 64)
+				ts.Duration = time.Duration(duration * timeBase * float64(time.Second))
+			}
+		} else {
+			seconds, _ := strconv.ParseFloat(match[2], 64)
+			ts.Time = time.Duration(seconds * float64(time.Second))
+		}
+		select {
+		case ch <- ts:
+		case <-stop:
+			stopped = true
+		}
+	}
+	// Make sure the process never blocks writing its log.
+	io.Copy(ioutil.Discard, r)
+}
@@ -3,7 +3,9 @@ package ffmpego
 import (
 	"io"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestVideoReader(t *testing.T) {
@@ -22,6 +24,58 @@ func TestVideoReaderResampled(t *testing.T) {
 	testVideoReader(t, reader, 40)
 }
 
+func TestVideoReaderTimestamps(t *testing.T) {
+	reader, err := NewVideoReaderWithOptions(filepath.Join("test_data", "test_video.mp4"),
+		&VideoReaderOptions{Start: time.Second, Timestamps: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	for i := 0; true; i++ {
+		frame, err := reader.ReadTimedFrame()
+		if err == io.EOF {
+			if i != 12 {
+				t.Errorf("expected 12 frames but got %d", i)
+			}
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		expected := time.Second + time.Duration(i)*time.Second/12
+		if diff := frame.Time - expected; diff > time.Millisecond || diff < -time.Millisecond {
+			t.Errorf("frame %d: expected time %v but got %v", i, expected, frame.Time)
+		}
+	}
+}
+
+func TestParseShowinfoLog(t *testing.T) {
+	log := `[Parsed_showinfo_0 @ 0x55d1] config in time_base: 1/100, frame_rate: 0/1
+[Parsed_showinfo_0 @ 0x55d1] n:   0 pts:      0 pts_time:0       duration:     10 duration_time:0.1     fmt:bgra
+[Parsed_showinfo_0 @ 0x55d1] color_range:unknown color_space:unknown
+[Parsed_showinfo_0 @ 0x55d1] n:   1 pts:     10 pts_time:0.1     duration:     50 duration_time:0.5     fmt:bgra
+frame=    2 fps=0.0 q=-0.0 Lsize=       0kB time=00:00:00.60 bitrate=   0.0kbits/s speed=N/A
+`
+	ch := make(chan *frameTimestamp, 10)
+	parseShowinfoLog(strings.NewReader(log), ch, make(chan struct{}))
+	close(ch)
+	var timestamps []*frameTimestamp
+	for ts := range ch {
+		timestamps = append(timestamps, ts)
+	}
+	expected := []frameTimestamp{
+		{Time: 0, Duration: 100 * time.Millisecond},
+		{Time: 100 * time.Millisecond, Duration: 500 * time.Millisecond},
+	}
+	if len(timestamps) != len(expected) {
+		t.Fatalf("expected %d timestamps but got %d", len(expected), len(timestamps))
+	}
+	for i, ts := range timestamps {
+		if *ts != expected[i] {
+			t.Errorf("timestamp %d: expected %v but got %v", i, expected[i], *ts)
+		}
+	}
+}
+
 func testVideoReader(t *testing.T, reader *VideoReader, expectedFrames int) {
 	defer func() {
 		if err := reader.Close(); err != nil {
@@ -5,6 +5,7 @@ import (
 	"image"
 	"io"
 	"os/exec"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -15,101 +16,362 @@ type VideoWriter struct {
 	writer  io.WriteCloser
 	width   int
 	height  int
+
+	// Only used for variable frame rate.
+	matroska      *matroskaWriter
+	frameDuration time.Duration
+	nextTime      time.Duration
+
+	// A frame written with WriteFrameAt is held until the
+	// next frame, which determines its duration.
+	pendingData []byte
+	pendingTime time.Duration
+
+	// Temporary files which are removed once the writer is
+	// closed.
+	tempFiles []string
 }
 
 // NewVideoWriter creates a VideoWriter which is encoding
 // to the given file.
 func NewVideoWriter(path string, width, height int, fps float64) (*VideoWriter, error) {
-	vw, err := newVideoWriter(path, width, height, fps)
-	if err != nil {
-		err = errors.Wrap(err, "write video")
-	}
-	return vw, err
+	return NewVideoWriterWithOptions(path, width, height, fps, &VideoWriterOptions{})
 }
 
 // NewVideoWriterWithAudio creates a VideoWriter which
 // copies audio from an existing video or audio file.
 func NewVideoWriterWithAudio(path string, width, height int, fps float64, audioFile string) (*VideoWriter, error) {
-	vw, err := newVideoWriter(
-		path, width, height, fps,
-		// Copy audio from input file.
-		"-i", audioFile, "-c:a", "copy",
-		// Map video from first input, audio from second.
-		"-map", "0:v:0", "-map", "1:a:0?",
-	)
+	vw, err := newVideoWriter(path, width, height, fps, &VideoWriterOptions{AudioFile: audioFile})
 	if err != nil {
 		err = errors.Wrap(err, "write video with audio")
 	}
 	return vw, err
 }
 
-func newVideoWriter(path string, width, height int, fps float64, extraFlags ...string) (*VideoWriter, error) {
+// VideoWriterOptions configures how a VideoWriter encodes
+// a file.
+type VideoWriterOptions struct {
+	// Encoding configures the video encoder.
+	// If nil, DefaultVideoEncoding() is used.
+	Encoding *VideoEncoding
+
+	// AudioFile, if specified, is a video or audio file to
+	// take an audio track from.
+	AudioFile string
+
+	// AudioEncoding configures how the audio from AudioFile
+	// is encoded. If nil, the audio is copied without
+	// re-encoding it.
+	AudioEncoding *AudioEncoding
+
+	// VariableFrameRate allows every frame to be shown for
+	// a different amount of time using WriteFrameDuration
+	// or WriteFrameAt.
+	//
+	// Frames written with WriteFrame are shown for 1/fps
+	// seconds.
+	VariableFrameRate bool
+
+	// Fragmented writes MP4 and MOV files as a series of
+	// self-contained fragments, so that everything up to the
+	// last keyframe can be read if the writer is never
+	// closed, for example because the process was killed.
+	//
+	// Such files can be made into regular files by Recover.
+	// Other containers, such as Matroska, are unaffected,
+	// since they are already readable when cut off.
+	Fragmented bool
+
+	// Metadata sets global tags of the output, such as
+	// "title" or "creation_time".
+	Metadata Metadata
+
+	// VideoMetadata and AudioMetadata set tags of the video
+	// and audio streams, such as "language".
+	VideoMetadata Metadata
+	AudioMetadata Metadata
+
+	// Chapters, if non-empty, divides the output into named
+	// sections.
+	Chapters []*Chapter
+
+	// Subtitles are added to the output as soft subtitle
+	// tracks, which players can turn on and off.
+	Subtitles []*SubtitleTrack
+}
+
+// NewVideoWriterWithOptions creates a VideoWriter with
+// custom encoding options.
+func NewVideoWriterWithOptions(path string, width, height int, fps float64,
+	opts *VideoWriterOptions) (*VideoWriter, error) {
+	vw, err := newVideoWriter(path, width, height, fps, opts)
+	if err != nil {
+		err = errors.Wrap(err, "write video")
+	}
+	return vw, err
+}
+
+func newVideoWriter(path string, width, height int, fps float64, opts *VideoWriterOptions) (*VideoWriter, error) {
 	stream, err := CreateChildStream(false)
 	if err != nil {
 		return nil, err
 	}
-	flags := []string{
-		"-y",
-		// Video format
-		"-r", fmt.Sprintf("%f", fps),
-		"-s", fmt.Sprintf("%dx%d", width, height),
-		"-pix_fmt", "rgb24", "-f", "rawvideo",
-		// Video input and parameters
-		"-probesize", "32", "-thread_queue_size", "10000", "-i", stream.ResourceURL(),
-	}
-	flags = append(flags, extraFlags...)
-	flags = append(
-		flags,
-		// Output parameters
-		"-c:v", "libx264", "-preset", "fast", "-crf", "18",
-		"-pix_fmt", "yuv420p", "-vf", "pad=ceil(iw/2)*2:ceil(ih/2)*2",
-	)
+	var flags []string
+	if opts.VariableFrameRate {
+		flags = []string{
+			"-y",
+			// Frames are wrapped in a container with timestamps.
+			"-f", "matroska",
+			"-thread_queue_size", "10000", "-i", stream.ResourceURL(),
+			"-vsync", "passthrough",
+		}
+	} else {
+		flags = append([]string{"-y"}, rawVideoInputArgs(stream.ResourceURL(), width, height, fps)...)
+	}
+	// Inputs after the frames are numbered in order.
+	inputIndex := 1
+	if opts.AudioFile != "" {
+		flags = append(flags, "-i", opts.AudioFile)
+		inputIndex++
+	}
+	var tempFiles []string
+	var chapterFlags []string
+	if len(opts.Chapters) > 0 {
+		chapterFile, err := writeChapterFile(opts.Chapters)
+		if err != nil {
+			stream.Cancel()
+			return nil, err
+		}
+		tempFiles = append(tempFiles, chapterFile)
+		var chapterInput []string
+		chapterInput, chapterFlags = chapterInputArgs(chapterFile, inputIndex)
+		flags = append(flags, chapterInput...)
+		inputIndex++
+	}
+	subtitlePaths, subtitleTemps, err := subtitleInputs(opts.Subtitles)
+	tempFiles = append(tempFiles, subtitleTemps...)
+	if err != nil {
+		stream.Cancel()
+		removeTempFiles(tempFiles)
+		return nil, err
+	}
+	for _, subtitlePath := range subtitlePaths {
+		flags = append(flags, "-i", subtitlePath)
+	}
+	if opts.AudioFile != "" || len(opts.Subtitles) > 0 {
+		flags = append(flags, "-map", "0:v:0")
+	}
+	if opts.AudioFile != "" {
+		audioEncoding := opts.AudioEncoding
+		if audioEncoding == nil {
+			audioEncoding = &AudioEncoding{Codec: "copy"}
+		}
+		flags = append(flags, audioEncoding.flags()...)
+		// Map audio from second input.
+		flags = append(flags, "-map", "1:a:0?")
+	}
+	flags = append(flags, subtitleOutputArgs(opts.Subtitles, inputIndex, path)...)
+	flags = append(flags, chapterFlags...)
+	flags = append(flags, videoEncodingFlags(opts.Encoding)...)
+	if opts.Fragmented {
+		flags = append(flags, fragmentedFlags(path)...)
+	}
+	audioMetadata := opts.AudioMetadata
+	if opts.AudioFile == "" {
+		audioMetadata = nil
+	}
+	flags = append(flags, outputMetadataFlags(opts.Metadata, opts.VideoMetadata, audioMetadata)...)
 	flags = append(flags, path)
 	cmd := exec.Command("ffmpeg", flags...)
 	cmd.ExtraFiles = stream.ExtraFiles()
 	if err := cmd.Start(); err != nil {
 		stream.Cancel()
+		removeTempFiles(tempFiles)
 		return nil, err
 	}
 	writer, err := stream.Connect()
 	if err != nil {
 		cmd.Process.Kill()
+		cmd.Wait()
+		removeTempFiles(tempFiles)
 		return nil, err
 	}
-	return &VideoWriter{
-		command: cmd,
-		writer:  writer,
-		width:   width,
-		height:  height,
-	}, nil
+	vw := &VideoWriter{
+		command:   cmd,
+		writer:    writer,
+		width:     width,
+		height:    height,
+		tempFiles: tempFiles,
+	}
+	if opts.VariableFrameRate {
+		vw.matroska = newMatroskaWriter(writer, width, height)
+		vw.frameDuration = time.Duration(float64(time.Second) / fps)
+		if err := vw.matroska.WriteHeader(); err != nil {
+			writer.Close()
+			cmd.Process.Kill()
+			cmd.Wait()
+			removeTempFiles(tempFiles)
+			return nil, err
+		}
+	}
+	return vw, nil
+}
+
+// rawVideoInputArgs gets the input flags for reading rgb24
+// frames written by a VideoWriter.
+func rawVideoInputArgs(url string, width, height int, fps float64) []string {
+	return []string{
+		// Video format
+		"-r", fmt.Sprintf("%f", fps),
+		"-s", fmt.Sprintf("%dx%d", width, height),
+		"-pix_fmt", "rgb24", "-f", "rawvideo",
+		// Video input and parameters
+		"-probesize", "32", "-thread_queue_size", "10000", "-i", url,
+	}
+}
+
+// videoEncodingFlags gets the output flags for encoding
+// frames of any size.
+func videoEncodingFlags(encoding *VideoEncoding) []string {
+	if encoding == nil {
+		encoding = DefaultVideoEncoding()
+	}
+	flags := encoding.flags()
+	if encoding.needsEvenSize() {
+		flags = append(flags, "-vf", "pad=ceil(iw/2)*2:ceil(ih/2)*2")
+	}
+	return flags
 }
 
 // WriteFrame adds a frame to the current video.
 func (v *VideoWriter) WriteFrame(img image.Image) error {
+	if v.matroska != nil {
+		return v.WriteFrameDuration(img, v.frameDuration)
+	}
+	data, err := v.frameData(img)
+	if err != nil {
+		return errors.Wrap(err, "write frame")
+	}
+	if _, err := v.writer.Write(data); err != nil {
+		return errors.Wrap(err, "write frame")
+	}
+	return nil
+}
+
+// WriteFrameDuration adds a frame to the current video
+// which is shown for the given amount of time.
+//
+// The writer must have been created with the
+// VariableFrameRate option.
+func (v *VideoWriter) WriteFrameDuration(img image.Image, duration time.Duration) error {
+	if v.matroska == nil {
+		panic("variable frame rate was not enabled for this writer")
+	}
+	if duration <= 0 {
+		return errors.New("write frame: duration must be positive")
+	}
+	data, err := v.frameData(img)
+	if err != nil {
+		return errors.Wrap(err, "write frame")
+	}
+	if err := v.flushPending(v.frameDuration); err != nil {
+		return errors.Wrap(err, "write frame")
+	}
+	if err := v.matroska.WriteFrame(v.nextTime, duration, data); err != nil {
+		return errors.Wrap(err, "write frame")
+	}
+	v.nextTime += duration
+	return nil
+}
+
+// WriteFrameAt adds a frame to the current video with an
+// explicit presentation timestamp.
+//
+// Each frame is shown until the timestamp of the next
+// frame. The final frame is shown for 1/fps seconds.
+// Timestamps must be increasing, and the video starts at
+// the timestamp of the first frame.
+//
+// The writer must have been created with the
+// VariableFrameRate option.
+func (v *VideoWriter) WriteFrameAt(img image.Image, pts time.Duration) error {
+	if v.matroska == nil {
+		panic("variable frame rate was not enabled for this writer")
+	}
+	if v.pendingData != nil {
+		if pts <= v.pendingTime {
+			return fmt.Errorf("write frame: timestamp %v is not after previous timestamp %v",
+				pts, v.pendingTime)
+		}
+	} else if pts < v.nextTime {
+		return fmt.Errorf("write frame: timestamp %v is before end of previous frame %v",
+			pts, v.nextTime)
+	}
+	data, err := v.frameData(img)
+	if err != nil {
+		return errors.Wrap(err, "write frame")
+	}
+	if v.pendingData != nil {
+		if err := v.flushPending(pts - v.pendingTime); err != nil {
+			return errors.Wrap(err, "write frame")
+		}
+	}
+	v.pendingData = data
+	v.pendingTime = pts
+	return nil
+}
+
+// flushPending writes the frame held by WriteFrameAt, if
+// there is one.
+func (v *VideoWriter) flushPending(duration time.Duration) error {
+	if v.pendingData == nil {
+		return nil
+	}
+	data := v.pendingData
+	v.pendingData = nil
+	if err := v.matroska.WriteFrame(v.pendingTime, duration, data); err != nil {
+		return err
+	}
+	v.nextTime = v.pendingTime + duration
+	return nil
+}
+
+// frameData converts an image to rgb24 pixel data.
+func (v *VideoWriter) frameData(img image.Image) ([]byte, error) {
+	return rgbFrameData(img, v.width, v.height)
+}
+
+// rgbFrameData converts an image to raw rgb24 pixels,
+// checking that it has the expected size.
+func rgbFrameData(img image.Image, width, height int) ([]byte, error) {
 	bounds := img.Bounds()
-	if bounds.Dx() != v.width || bounds.Dy() != v.height {
-		return fmt.Errorf("write frame: image size (%dx%d) does not match video size (%dx%d)",
-			bounds.Dx(), bounds.Dy(), v.width, v.height)
+	if bounds.Dx() != width || bounds.Dy() != height {
+		return nil, fmt.Errorf("image size (%dx%d) does not match video size (%dx%d)",
+			bounds.Dx(), bounds.Dy(), width, height)
 	}
-	data := make([]byte, 0, 3*v.width*v.height)
+	data := make([]byte, 0, 3*width*height)
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 			r, g, b, _ := img.At(x, y).RGBA()
 			data = append(data, uint8(r>>8), uint8(g>>8), uint8(b>>8))
 		}
 	}
-	_, err := v.writer.Write(data)
-	if err != nil {
-		return errors.Wrap(err, "write frame")
-	}
-	return nil
+	return data, nil
 }
 
 // Close closes the video file and waits for encoding to
 // complete.
 func (v *VideoWriter) Close() error {
+	var flushErr error
+	if v.matroska != nil {
+		flushErr = v.flushPending(v.frameDuration)
+	}
 	v.writer.Close()
 	err := v.command.Wait()
+	removeTempFiles(v.tempFiles)
+	if err == nil {
+		err = flushErr
+	}
 	if err != nil {
 		return errors.Wrap(err, "close video writer")
 	}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestVideoWriter(t *testing.T) {
@@ -80,3 +81,117 @@ func TestVideoWriterWithAudio(t *testing.T) {
 		t.Errorf("bad video info: %#v", videoInfo)
 	}
 }
+
+func TestVideoWriterVariableFrameRate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-video-writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	durations := []time.Duration{
+		100 * time.Millisecond,
+		300 * time.Millisecond,
+		50 * time.Millisecond,
+		200 * time.Millisecond,
+	}
+	for _, name := range []string{"out.mkv", "out.gif"} {
+		outPath := filepath.Join(dir, name)
+		var vw *VideoWriter
+		if name == "out.gif" {
+			vw, err = NewAnimationWriter(outPath, 16, 16, 100*time.Millisecond, &AnimationOptions{})
+		} else {
+			vw, err = NewVideoWriterWithOptions(outPath, 16, 16, 10, &VideoWriterOptions{
+				VariableFrameRate: true,
+			})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, duration := range durations {
+			frame := image.NewGray(image.Rect(0, 0, 16, 16))
+			for j := range frame.Pix {
+				frame.Pix[j] = uint8(i * 60)
+			}
+			if err := vw.WriteFrameDuration(frame, duration); err != nil {
+				vw.Close()
+				t.Fatal(err)
+			}
+		}
+		if err := vw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		vr, err := NewVideoReaderWithOptions(outPath, &VideoReaderOptions{Timestamps: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expectedTime time.Duration
+		for i, duration := range durations {
+			frame, err := vr.ReadTimedFrame()
+			if err != nil {
+				vr.Close()
+				t.Fatal(err)
+			}
+			if frame.Time != expectedTime {
+				t.Errorf("%s: frame %d: expected time %v but got %v", name, i, expectedTime,
+					frame.Time)
+			}
+			expectedTime += duration
+		}
+		vr.Close()
+	}
+}
+
+func TestVideoWriterWriteFrameAt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-video-writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outPath := filepath.Join(dir, "out.mkv")
+	vw, err := NewVideoWriterWithOptions(outPath, 16, 16, 10, &VideoWriterOptions{
+		VariableFrameRate: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamps := []time.Duration{
+		time.Second,
+		1100 * time.Millisecond,
+		1450 * time.Millisecond,
+		1500 * time.Millisecond,
+	}
+	for i, pts := range timestamps {
+		frame := image.NewGray(image.Rect(0, 0, 16, 16))
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(i * 60)
+		}
+		if err := vw.WriteFrameAt(frame, pts); err != nil {
+			vw.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := vw.WriteFrameAt(image.NewGray(image.Rect(0, 0, 16, 16)), time.Second); err == nil {
+		t.Error("expected error for decreasing timestamp")
+	}
+	if err := vw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	vr, err := NewVideoReaderWithOptions(outPath, &VideoReaderOptions{Timestamps: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vr.Close()
+	for i, pts := range timestamps {
+		frame, err := vr.ReadTimedFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := pts - timestamps[0]; frame.Time != expected {
+			t.Errorf("frame %d: expected time %v but got %v", i, expected, frame.Time)
+		}
+	}
+}